@@ -1,5 +1,11 @@
 package sqlite3
 
+import (
+	"context"
+	"errors"
+	"time"
+)
+
 // Backup is an handle to an ongoing online backup operation.
 //
 // https://sqlite.org/c3ref/backup.html
@@ -33,11 +39,7 @@ func (src *Conn) Backup(srcDB, dstURI string) error {
 //
 // https://sqlite.org/backup.html
 func (dst *Conn) Restore(dstDB, srcURI string) error {
-	src, err := dst.openDB(srcURI, OPEN_READONLY|OPEN_URI)
-	if err != nil {
-		return err
-	}
-	b, err := dst.backupInit(dst.handle, dstDB, src, "main")
+	b, err := dst.RestoreInit(dstDB, srcURI)
 	if err != nil {
 		return err
 	}
@@ -61,6 +63,21 @@ func (src *Conn) BackupInit(srcDB, dstURI string) (*Backup, error) {
 	return src.backupInit(dst, "main", src.handle, srcDB)
 }
 
+// RestoreInit initializes a backup operation to restore one database from another.
+//
+// RestoreInit opens the SQLite database file srcURI,
+// then initializes a backup that copies the contents of its "main" database
+// to dstDB on the dst connection.
+//
+// https://sqlite.org/c3ref/backup_finish.html#sqlite3backupinit
+func (dst *Conn) RestoreInit(dstDB, srcURI string) (*Backup, error) {
+	src, err := dst.openDB(srcURI, OPEN_READONLY|OPEN_URI)
+	if err != nil {
+		return nil, err
+	}
+	return dst.backupInit(dst.handle, dstDB, src, "main")
+}
+
 func (c *Conn) backupInit(dst ptr_t, dstName string, src ptr_t, srcName string) (*Backup, error) {
 	defer c.arena.mark()()
 	dstPtr := c.arena.string(dstName)
@@ -132,3 +149,66 @@ func (b *Backup) PageCount() int {
 	n := int32(b.c.call("sqlite3_backup_pagecount", stk_t(b.handle)))
 	return int(n)
 }
+
+// BackupOptions configure [Backup.Run],
+// [Conn.BackupTo] and [Conn.RestoreFrom].
+type BackupOptions struct {
+	// Pages is the number of pages copied by each [Backup.Step].
+	// If zero or negative, all pages are copied in a single step.
+	Pages int
+	// Sleep is the time to wait between steps,
+	// so that other connections can write to the database.
+	Sleep time.Duration
+	// Progress, if set, is called after each step,
+	// with [Backup.Remaining] and [Backup.PageCount].
+	Progress func(remaining, pageCount int)
+}
+
+// Run steps the backup until it's done.
+//
+// Between steps, Run waits for opts.Sleep,
+// and retries steps that fail with [BUSY] or [LOCKED].
+// The backup is interrupted if ctx is done.
+func (b *Backup) Run(ctx context.Context, opts *BackupOptions) error {
+	var o BackupOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Pages <= 0 {
+		o.Pages = -1
+	}
+
+	old := b.c.SetInterrupt(ctx)
+	defer b.c.SetInterrupt(old)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		done, err := b.Step(o.Pages)
+		if err != nil && !errors.Is(err, BUSY) && !errors.Is(err, LOCKED) {
+			return err
+		}
+		if o.Progress != nil {
+			o.Progress(b.Remaining(), b.PageCount())
+		}
+		if done {
+			return nil
+		}
+
+		// Don't spin while the database is busy.
+		sleep := o.Sleep
+		if err != nil {
+			sleep = max(sleep, time.Millisecond)
+		}
+		if sleep > 0 {
+			t := time.NewTimer(sleep)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return ctx.Err()
+			case <-t.C:
+			}
+		}
+	}
+}
@@ -0,0 +1,505 @@
+package sqlite3
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/ncruces/go-sqlite3/internal/util"
+)
+
+// Batch holds the rows fetched by [Stmt.StepBatch].
+// Values are decoded as: int64, float64, string, []byte, or nil.
+//
+// Fetching rows in batches avoids the overhead of
+// reading result columns one value at a time.
+type Batch struct {
+	vals []any
+	blob []byte
+	cols int
+}
+
+// Len returns the number of rows in the batch.
+func (b *Batch) Len() int {
+	if b.cols == 0 {
+		return 0
+	}
+	return len(b.vals) / b.cols
+}
+
+// Row returns the values of row i of the batch.
+// Rows are numbered from 0 to [Batch.Len] - 1.
+//
+// The returned slice, and any []byte values in it,
+// must not be modified, and are only valid
+// until the next call to [Stmt.StepBatch] with this batch,
+// or until the statement is reset or closed.
+func (b *Batch) Row(i int) []any {
+	return b.vals[i*b.cols : (i+1)*b.cols : (i+1)*b.cols]
+}
+
+// Columns populates result columns from a row of the batch into dest,
+// just like [Stmt.Columns].
+// The number of values in dest must be the number of columns.
+func (b *Batch) Columns(row int, dest ...any) error {
+	if len(dest) != b.cols {
+		return MISUSE
+	}
+	copy(dest, b.Row(row))
+	return nil
+}
+
+func (b *Batch) reset(cols int) {
+	clear(b.vals)
+	b.vals = b.vals[:0]
+	b.blob = b.blob[:0]
+	b.cols = cols
+}
+
+// appendBlob copies a BLOB into the batch.
+// BLOBs are copied into a shared buffer, so that
+// fetching a batch doesn't allocate for each of them.
+func (b *Batch) appendBlob(buf []byte) []byte {
+	if len(buf) == 0 {
+		return []byte{}
+	}
+	if len(b.blob)+len(buf) > cap(b.blob) {
+		// Values already decoded keep the old buffer.
+		b.blob = make([]byte, 0, max(2*cap(b.blob), len(buf), 4096))
+	}
+	i := len(b.blob)
+	b.blob = append(b.blob, buf...)
+	return b.blob[i:len(b.blob):len(b.blob)]
+}
+
+const (
+	batchRows  = 1024
+	batchBytes = 64 * 1024
+)
+
+// StepBatch evaluates the statement for up to n rows,
+// replacing the contents of b with them.
+// It returns false when the statement is done, or fails;
+// the last batch may hold rows even when StepBatch returns false.
+// Call [Stmt.Err] to check for errors:
+//
+//	var batch sqlite3.Batch
+//	for more := true; more; {
+//		more = stmt.StepBatch(100, &batch)
+//		for i := range batch.Len() {
+//			row := batch.Row(i)
+//			// ...
+//		}
+//	}
+//	if err := stmt.Err(); err != nil {
+//		// ...
+//	}
+//
+// After StepBatch, the statement is positioned at the last row of the batch,
+// but column values should be read from the batch.
+//
+// Each batch has a single row if the statement has a [Budget],
+// or uses [Stmt.BindReader], or if the SQLite binary
+// doesn't support fetching rows in batches.
+//
+// https://sqlite.org/c3ref/step.html
+func (s *Stmt) StepBatch(n int, b *Batch) bool {
+	b.reset(s.ColumnCount())
+	n = min(max(n, 1), batchRows)
+
+	// Budgets and bound readers need to see each step,
+	// and older builds of SQLite don't support batches.
+	const name = "sqlite3_step_batch_go"
+	fn := s.c.getfn(name)
+	s.c.putfn(name, fn) // Also caches a missing export.
+	if fn == nil || s.budget != nil || s.readers != nil {
+		return s.stepBatch(b)
+	}
+
+	defer s.c.arena.mark()()
+	ptr := s.c.arena.new(16)
+	util.Write32[ptr_t](s.c.mod, ptr+0, 0)
+	util.Write32[int32](s.c.mod, ptr+4, 0)
+
+	s.c.checkInterrupt(s.c.handle)
+	rc := res_t(s.c.call(name, stk_t(s.handle),
+		stk_t(n), stk_t(batchBytes), stk_t(ptr)))
+
+	buf := util.Read32[ptr_t](s.c.mod, ptr+0)
+	size := util.Read32[int32](s.c.mod, ptr+8)
+	rows := util.Read32[int32](s.c.mod, ptr+12)
+	b.decode(util.View(s.c.mod, buf, int64(size)), int(rows))
+	s.c.free(buf)
+
+	switch rc {
+	case _ROW:
+		s.err = nil
+		return true
+	case _DONE:
+		s.err = nil
+	default:
+		s.err = s.c.error(rc)
+	}
+	return false
+}
+
+// stepBatch fetches a batch with a single row.
+// Without a native batch, reading ahead costs more than it saves,
+// and values can be used as returned by [Stmt.Columns].
+func (s *Stmt) stepBatch(b *Batch) bool {
+	if !s.Step() {
+		return false
+	}
+	b.vals = append(b.vals, make([]any, b.cols)...)
+	if err := s.Columns(b.vals...); err != nil {
+		b.vals = b.vals[:0]
+		s.err = err
+		return false
+	}
+	return true
+}
+
+// decode decodes rows serialized by sqlite3_step_batch_go.
+func (b *Batch) decode(buf []byte, rows int) {
+	b.vals = append(b.vals, make([]any, rows*b.cols)...)
+	for i := range b.vals {
+		typ := Datatype(buf[0])
+		buf = buf[1:]
+		switch typ {
+		case INTEGER:
+			b.vals[i] = int64(binary.LittleEndian.Uint64(buf))
+			buf = buf[8:]
+		case FLOAT:
+			b.vals[i] = math.Float64frombits(binary.LittleEndian.Uint64(buf))
+			buf = buf[8:]
+		case TEXT, BLOB:
+			n := binary.LittleEndian.Uint32(buf)
+			data := buf[4 : 4+n]
+			buf = buf[4+n:]
+			if typ == TEXT {
+				b.vals[i] = string(data)
+			} else {
+				b.vals[i] = b.appendBlob(data)
+			}
+		default:
+			b.vals[i] = nil
+		}
+	}
+}
+
+// RowError is returned by [Stmt.ExecBatch] and [Stmt.ExecColumns]
+// when a row fails.
+// Rows before Row were executed.
+type RowError struct {
+	Row int
+	Err error
+}
+
+// Error implements the error interface.
+func (e *RowError) Error() string {
+	return "sqlite3: row " + strconv.Itoa(e.Row) + ": " + e.Err.Error()
+}
+
+// Unwrap returns the error of the row.
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// ExecBatch executes the statement once for each row of rows,
+// binding the values of each row to the parameters of the statement by position,
+// as [Stmt.BindNamed] binds values by type.
+// Each row must have exactly one value for each parameter.
+//
+// Rows are serialized and executed in bulk, avoiding
+// the overhead of binding values one at a time.
+// Execution stops at the first row that fails,
+// with a [*RowError] that reports it.
+// Bindings are cleared when ExecBatch returns.
+//
+// https://sqlite.org/c3ref/bind_blob.html
+func (s *Stmt) ExecBatch(rows [][]any) error {
+	params := s.BindCount()
+	for i, row := range rows {
+		if len(row) != params {
+			return &RowError{i, util.RangeErr}
+		}
+	}
+	return s.execBatch(len(rows), params, rowArgs(rows))
+}
+
+// ExecColumns is like [Stmt.ExecBatch], but arguments are given by column:
+// cols has one slice for each parameter of the statement,
+// all of the same length, holding its value for each row.
+//
+// Slices of int64, int, float64, bool, string and []byte
+// are serialized without boxing their values.
+//
+// https://sqlite.org/c3ref/bind_blob.html
+func (s *Stmt) ExecColumns(cols ...any) error {
+	if len(cols) != s.BindCount() {
+		return util.RangeErr
+	}
+	args := make(colArgs, len(cols))
+	rows := 0
+	for i, col := range cols {
+		v := reflect.ValueOf(col)
+		if v.Kind() != reflect.Slice {
+			return util.ValueErr
+		}
+		if i == 0 {
+			rows = v.Len()
+		} else if v.Len() != rows {
+			return util.RangeErr
+		}
+		args[i] = columnArg(col, v)
+	}
+	return s.execBatch(rows, len(cols), args)
+}
+
+// batchArgs are the arguments of a batch.
+type batchArgs interface {
+	// append serializes an argument,
+	// or reports if it can't be serialized.
+	append(buf []byte, row, param int) ([]byte, bool)
+	// bind binds an argument to parameter param+1 of s.
+	bind(s *Stmt, row, param int) error
+}
+
+type rowArgs [][]any
+
+func (a rowArgs) append(buf []byte, row, param int) ([]byte, bool) {
+	return appendArg(buf, a[row][param])
+}
+
+func (a rowArgs) bind(s *Stmt, row, param int) error {
+	return s.bind(param+1, a[row][param])
+}
+
+type colArgs []colArg
+
+type colArg struct {
+	append func(buf []byte, row int) ([]byte, bool)
+	bind   func(s *Stmt, param, row int) error
+}
+
+func (a colArgs) append(buf []byte, row, param int) ([]byte, bool) {
+	return a[param].append(buf, row)
+}
+
+func (a colArgs) bind(s *Stmt, row, param int) error {
+	return a[param].bind(s, param+1, row)
+}
+
+// columnArg serializes the values of typed slices directly,
+// and boxes the values of other slices.
+func columnArg(col any, v reflect.Value) colArg {
+	switch c := col.(type) {
+	case []int64:
+		return colArg{
+			func(buf []byte, row int) ([]byte, bool) { return appendInt(buf, c[row]), true },
+			func(s *Stmt, param, row int) error { return s.BindInt64(param, c[row]) },
+		}
+	case []int:
+		return colArg{
+			func(buf []byte, row int) ([]byte, bool) { return appendInt(buf, int64(c[row])), true },
+			func(s *Stmt, param, row int) error { return s.BindInt(param, c[row]) },
+		}
+	case []float64:
+		return colArg{
+			func(buf []byte, row int) ([]byte, bool) { return appendFloat(buf, c[row]), true },
+			func(s *Stmt, param, row int) error { return s.BindFloat(param, c[row]) },
+		}
+	case []bool:
+		return colArg{
+			func(buf []byte, row int) ([]byte, bool) { return appendBool(buf, c[row]), true },
+			func(s *Stmt, param, row int) error { return s.BindBool(param, c[row]) },
+		}
+	case []string:
+		return colArg{
+			func(buf []byte, row int) ([]byte, bool) { return appendData(buf, TEXT, c[row]), true },
+			func(s *Stmt, param, row int) error { return s.BindText(param, c[row]) },
+		}
+	case [][]byte:
+		return colArg{
+			func(buf []byte, row int) ([]byte, bool) { return appendData(buf, BLOB, c[row]), true },
+			func(s *Stmt, param, row int) error { return s.BindBlob(param, c[row]) },
+		}
+	}
+	value := func(row int) any { return v.Index(row).Interface() }
+	return colArg{
+		func(buf []byte, row int) ([]byte, bool) { return appendArg(buf, value(row)) },
+		func(s *Stmt, param, row int) error { return s.bind(param, value(row)) },
+	}
+}
+
+// appendArg serializes a value as sqlite3_exec_batch_go expects,
+// or reports if it can't be serialized.
+// Values are converted as [Stmt.bind] converts them.
+func appendArg(buf []byte, value any) ([]byte, bool) {
+	switch v := value.(type) {
+	case nil:
+		return append(buf, byte(NULL)), true
+	case bool:
+		return appendBool(buf, v), true
+	case int:
+		return appendInt(buf, int64(v)), true
+	case int64:
+		return appendInt(buf, v), true
+	case float64:
+		return appendFloat(buf, v), true
+	case string:
+		return appendData(buf, TEXT, v), true
+	case []byte:
+		return appendData(buf, BLOB, v), true
+	case time.Time:
+		buf = append(buf, byte(TEXT), 0, 0, 0, 0)
+		i := len(buf)
+		buf = v.AppendFormat(buf, time.RFC3339Nano)
+		binary.LittleEndian.PutUint32(buf[i-4:], uint32(len(buf)-i))
+		return buf, true
+	case ZeroBlob, Value, util.JSON, util.PointerUnwrap, driver.Valuer:
+		return buf, false
+	}
+
+	v := reflect.ValueOf(value)
+	switch k := v.Kind(); {
+	case k == reflect.Interface || k == reflect.Pointer:
+		if v.IsNil() {
+			return append(buf, byte(NULL)), true
+		}
+		return appendArg(buf, v.Elem().Interface())
+	case v.CanInt():
+		return appendInt(buf, v.Int()), true
+	case v.CanUint():
+		if u := v.Uint(); u <= math.MaxInt64 {
+			return appendInt(buf, int64(u)), true
+		}
+	case v.CanFloat():
+		return appendFloat(buf, v.Float()), true
+	case k == reflect.Bool:
+		return appendBool(buf, v.Bool()), true
+	case k == reflect.String:
+		return appendData(buf, TEXT, v.String()), true
+	case k == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		return appendData(buf, BLOB, v.Bytes()), true
+	}
+	return buf, false
+}
+
+func appendInt(buf []byte, i int64) []byte {
+	buf = append(buf, byte(INTEGER))
+	return binary.LittleEndian.AppendUint64(buf, uint64(i))
+}
+
+func appendFloat(buf []byte, f float64) []byte {
+	buf = append(buf, byte(FLOAT))
+	return binary.LittleEndian.AppendUint64(buf, math.Float64bits(f))
+}
+
+func appendBool(buf []byte, b bool) []byte {
+	var i int64
+	if b {
+		i = 1
+	}
+	return appendInt(buf, i)
+}
+
+func appendData[T string | []byte](buf []byte, typ Datatype, data T) []byte {
+	buf = append(buf, byte(typ))
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(data)))
+	return append(buf, data...)
+}
+
+func (s *Stmt) execBatch(rows, params int, args batchArgs) error {
+	// Each row binds every parameter,
+	// so bindings only need to be cleared at the end.
+	defer s.ClearBindings()
+
+	// Budgets and bound readers need to see each step,
+	// and older builds of SQLite don't support batches.
+	const name = "sqlite3_exec_batch_go"
+	fn := s.c.getfn(name)
+	s.c.putfn(name, fn) // Also caches a missing export.
+	if fn == nil || s.budget != nil || s.readers != nil {
+		return s.execEach(rows, params, args)
+	}
+
+	var buf []byte
+	for row := 0; row < rows; {
+		// Serialize rows until the buffer is full,
+		// or a value can't be serialized.
+		ok := true
+		start := row
+		buf = buf[:0]
+		for ; row < rows && len(buf) < batchBytes; row++ {
+			n := len(buf)
+			for param := range params {
+				if buf, ok = args.append(buf, row, param); !ok {
+					break
+				}
+			}
+			if !ok {
+				buf = buf[:n]
+				break
+			}
+		}
+
+		if row > start {
+			if err := s.execRows(buf, start, row-start, params); err != nil {
+				return err
+			}
+		}
+		if !ok {
+			if err := s.execRow(args, row, params); err != nil {
+				return err
+			}
+			row++
+		}
+	}
+	return nil
+}
+
+// execEach binds and executes rows one at a time.
+func (s *Stmt) execEach(rows, params int, args batchArgs) error {
+	for row := range rows {
+		if err := s.execRow(args, row, params); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// execRows executes n serialized rows, starting at row.
+func (s *Stmt) execRows(buf []byte, row, n, params int) error {
+	defer s.c.arena.mark()()
+	donePtr := s.c.arena.new(4)
+	size := int64(max(len(buf), 1))
+	bufPtr := s.c.new(size)
+	defer s.c.free(bufPtr)
+	copy(util.View(s.c.mod, bufPtr, size), buf)
+
+	s.c.checkInterrupt(s.c.handle)
+	rc := res_t(s.c.call("sqlite3_exec_batch_go", stk_t(s.handle),
+		stk_t(n), stk_t(params), stk_t(bufPtr), stk_t(donePtr)))
+	if err := s.c.error(rc); err != nil {
+		done := util.Read32[int32](s.c.mod, donePtr)
+		return &RowError{row + int(done), err}
+	}
+	return nil
+}
+
+// execRow binds and executes a single row.
+func (s *Stmt) execRow(args batchArgs, row, params int) error {
+	for param := range params {
+		if err := args.bind(s, row, param); err != nil {
+			return &RowError{row, err}
+		}
+	}
+	if err := s.Exec(); err != nil {
+		return &RowError{row, err}
+	}
+	return nil
+}
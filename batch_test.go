@@ -0,0 +1,362 @@
+package sqlite3
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+func Test_appendArg(t *testing.T) {
+	t.Parallel()
+
+	type myInt int16
+	type myString string
+	args := []any{
+		nil, true, false, 1, int64(-2), 3.5, float32(4.5),
+		"text", []byte("blob"), []byte{}, myInt(7), myString("mine"),
+		new(int), (*int)(nil), uint8(9),
+		time.Date(2000, 1, 2, 3, 4, 5, 6, time.UTC),
+	}
+	want := []any{
+		nil, int64(1), int64(0), int64(1), int64(-2), 3.5, 4.5,
+		"text", []byte("blob"), []byte{}, int64(7), "mine",
+		int64(0), nil, int64(9),
+		"2000-01-02T03:04:05.000000006Z",
+	}
+
+	var buf []byte
+	for _, a := range args {
+		var ok bool
+		buf, ok = appendArg(buf, a)
+		if !ok {
+			t.Fatalf("appendArg(%#v) failed", a)
+		}
+	}
+
+	b := Batch{cols: len(args)}
+	b.decode(buf, 1)
+	if got := b.Row(0); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+
+	for _, a := range []any{ZeroBlob(1), uint64(1 << 63), struct{}{}} {
+		if _, ok := appendArg(nil, a); ok {
+			t.Errorf("appendArg(%#v) succeeded", a)
+		}
+	}
+}
+
+func Test_columnArg(t *testing.T) {
+	t.Parallel()
+
+	type myInt int16
+	cols := []any{
+		[]int64{1, -2}, []int{3, -4}, []float64{5.5, -6},
+		[]bool{true, false}, []string{"text", ""},
+		[][]byte{[]byte("blob"), nil}, []myInt{7, -8}, []any{nil, "any"},
+	}
+
+	for _, col := range cols {
+		v := reflect.ValueOf(col)
+		arg := columnArg(col, v)
+		for row := range v.Len() {
+			got, ok := arg.append(nil, row)
+			if !ok {
+				t.Fatalf("append(%#v, %d) failed", col, row)
+			}
+			want, _ := appendArg(nil, v.Index(row).Interface())
+			if !slices.Equal(got, want) {
+				t.Errorf("append(%#v, %d) = %v, want %v", col, row, got, want)
+			}
+		}
+	}
+}
+
+func TestStmt_StepBatch_native(t *testing.T) {
+	t.Parallel()
+
+	db, err := Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := db.needfn("sqlite3_step_batch_go"); err != nil {
+		t.Skip(err)
+	}
+
+	// Row 150 overflows, and fails the statement.
+	stmt, _, err := db.Prepare(`
+		WITH RECURSIVE c(x) AS (VALUES(1) UNION ALL SELECT x+1 FROM c LIMIT 200)
+		SELECT x, x/2.0, 'row ' || x, CAST(x AS BLOB), NULL, x'',
+			CASE x WHEN 150 THEN abs(-9223372036854775808) END
+		FROM c
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+
+	// Compare with fetching one row at a time.
+	fetch := func(step func(int, *Batch) bool) (rows [][]any, batches int, err error) {
+		stmt.Reset()
+		var batch Batch
+		for more := true; more; {
+			more = step(64, &batch)
+			batches++
+			for i := range batch.Len() {
+				row := append([]any(nil), batch.Row(i)...)
+				for i, v := range row {
+					if v, ok := v.([]byte); ok {
+						row[i] = slices.Clone(v)
+					}
+				}
+				rows = append(rows, row)
+			}
+		}
+		return rows, batches, stmt.Err()
+	}
+
+	want, _, wantErr := fetch(func(n int, b *Batch) bool {
+		b.reset(stmt.ColumnCount())
+		return stmt.stepBatch(b)
+	})
+	got, batches, gotErr := fetch(stmt.StepBatch)
+	if !errors.Is(gotErr, ERROR) || !errors.Is(wantErr, ERROR) {
+		t.Errorf("got %v, want %v", gotErr, wantErr)
+	}
+	if len(got) != 149 || batches != 3 {
+		t.Errorf("got %d rows in %d batches", len(got), batches)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Error("native and fallback rows differ")
+	}
+}
+
+func Test_execEach(t *testing.T) {
+	t.Parallel()
+	testExecBatch(t, func(s *Stmt, rows [][]any) error {
+		return s.execEach(len(rows), s.BindCount(), rowArgs(rows))
+	})
+}
+
+func Test_execBatch_native(t *testing.T) {
+	t.Parallel()
+	testExecBatch(t, func(s *Stmt, rows [][]any) error {
+		if err := s.c.needfn("sqlite3_exec_batch_go"); err != nil {
+			t.Skip(err)
+		}
+		return s.execBatch(len(rows), s.BindCount(), rowArgs(rows))
+	})
+}
+
+func Test_execBatch_columns(t *testing.T) {
+	t.Parallel()
+	testExecBatch(t, func(s *Stmt, rows [][]any) error {
+		return s.ExecColumns(rowColumns(rows)...)
+	})
+}
+
+// rowColumns transposes rows into columns of values.
+func rowColumns(rows [][]any) []any {
+	var cols [][]any
+	for _, row := range rows {
+		cols = slices.Grow(cols, len(row))[:len(row)]
+		for i, v := range row {
+			cols[i] = append(cols[i], v)
+		}
+	}
+	args := make([]any, len(cols))
+	for i, col := range cols {
+		args[i] = col
+	}
+	return args
+}
+
+func testExecBatch(t *testing.T, exec func(*Stmt, [][]any) error) {
+	db, err := Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = db.Exec(`CREATE TABLE test (id INTEGER PRIMARY KEY, data NOT NULL)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stmt, _, err := db.Prepare(`INSERT INTO test VALUES (?, ?)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+
+	count := func() (n int) {
+		t.Helper()
+		stmt, _, err := db.Prepare(`SELECT count(*) FROM test`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer stmt.Close()
+		if stmt.Step() {
+			n = stmt.ColumnInt(0)
+		}
+		if err := stmt.Err(); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	// Rows large enough to be executed in several chunks,
+	// and a value that can't be serialized.
+	blob := make([]byte, batchBytes/2)
+	err = exec(stmt, [][]any{
+		{1, blob}, {2, blob}, {3, ZeroBlob(10)}, {4, blob}, {5, "five"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := count(); n != 5 {
+		t.Errorf("got %d rows, want 5", n)
+	}
+
+	// Rows before the failing row are executed, and rows after it aren't.
+	for _, tt := range []struct {
+		rows [][]any
+		fail int
+		want error
+	}{
+		{[][]any{{6, "six"}, {1, "one"}, {7, "seven"}}, 1, CONSTRAINT_PRIMARYKEY},
+		{[][]any{{6, blob}, {7, blob}, {8, nil}, {9, "nine"}}, 2, CONSTRAINT_NOTNULL},
+		{[][]any{{6, "six"}, {7, ZeroBlob(1)}, {8, "eight"}, {1, "one"}}, 3, CONSTRAINT_PRIMARYKEY},
+	} {
+		err = db.Exec(`BEGIN`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = exec(stmt, tt.rows)
+		var rowErr *RowError
+		if !errors.As(err, &rowErr) || rowErr.Row != tt.fail {
+			t.Errorf("got %v, want row %d to fail", err, tt.fail)
+		}
+		if !errors.Is(err, tt.want) {
+			t.Errorf("got %v, want %v", err, tt.want)
+		}
+		if n := count(); n != 5+tt.fail {
+			t.Errorf("got %d rows, want %d", n, 5+tt.fail)
+		}
+		err = db.Exec(`ROLLBACK`)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// An interrupted batch fails at the first row.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	db.SetInterrupt(ctx)
+	err = exec(stmt, [][]any{{6, "six"}, {7, "seven"}})
+	db.SetInterrupt(context.Background())
+	var rowErr *RowError
+	if !errors.As(err, &rowErr) || rowErr.Row != 0 || !errors.Is(err, INTERRUPT) {
+		t.Errorf("got %v, want row 0 interrupted", err)
+	}
+	if n := count(); n != 5 {
+		t.Errorf("got %d rows, want 5", n)
+	}
+}
+
+func BenchmarkStmt_Step(b *testing.B) {
+	db, stmt := benchmarkRows(b)
+	defer db.Close()
+	defer stmt.Close()
+
+	row := make([]any, stmt.ColumnCount())
+	for range b.N {
+		for stmt.Step() {
+			stmt.Columns(row...)
+		}
+		stmt.Reset()
+	}
+}
+
+func BenchmarkStmt_StepBatch(b *testing.B) {
+	db, stmt := benchmarkRows(b)
+	defer db.Close()
+	defer stmt.Close()
+
+	var batch Batch
+	for range b.N {
+		for more := true; more; {
+			more = stmt.StepBatch(256, &batch)
+		}
+		stmt.Reset()
+	}
+}
+
+func benchmarkRows(b *testing.B) (*Conn, *Stmt) {
+	db, err := Open(":memory:")
+	if err != nil {
+		b.Fatal(err)
+	}
+	stmt, _, err := db.Prepare(`
+		WITH RECURSIVE c(x) AS (VALUES(1) UNION ALL SELECT x+1 FROM c LIMIT 10000)
+		SELECT x, x/2.0, 'row ' || x, CAST(x AS BLOB), NULL FROM c
+	`)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return db, stmt
+}
+
+func BenchmarkStmt_Exec(b *testing.B) {
+	db, stmt := benchmarkInsert(b)
+	defer db.Close()
+	defer stmt.Close()
+
+	for range b.N {
+		for i := range 10000 {
+			stmt.BindInt64(1, int64(i))
+			stmt.BindFloat(2, float64(i)/2)
+			stmt.BindText(3, "row")
+			stmt.Exec()
+		}
+	}
+}
+
+func BenchmarkStmt_ExecColumns(b *testing.B) {
+	db, stmt := benchmarkInsert(b)
+	defer db.Close()
+	defer stmt.Close()
+
+	ints := make([]int64, 10000)
+	floats := make([]float64, 10000)
+	texts := make([]string, 10000)
+	for i := range ints {
+		ints[i] = int64(i)
+		floats[i] = float64(i) / 2
+		texts[i] = "row"
+	}
+	for range b.N {
+		stmt.ExecColumns(ints, floats, texts)
+	}
+}
+
+func benchmarkInsert(b *testing.B) (*Conn, *Stmt) {
+	db, err := Open(":memory:")
+	if err != nil {
+		b.Fatal(err)
+	}
+	err = db.Exec(`CREATE TABLE test (a, b, c)`)
+	if err != nil {
+		b.Fatal(err)
+	}
+	stmt, _, err := db.Prepare(`INSERT INTO test VALUES (?, ?, ?)`)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return db, stmt
+}
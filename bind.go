@@ -0,0 +1,148 @@
+package sqlite3
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/ncruces/go-sqlite3/internal/util"
+)
+
+// StrictBinding sets whether [Stmt.BindNamed] and [Stmt.BindStruct]
+// fail when a parameter has no matching argument,
+// or an argument has no matching parameter.
+// By default, these are ignored, and unmatched parameters are left unchanged.
+func (s *Stmt) StrictBinding(strict bool) {
+	s.strict = strict
+}
+
+// BindNamed binds the values in args to the named parameters
+// of the prepared statement.
+//
+// A parameter named ":name", "@name" or "$name"
+// binds the value with that exact key, or with the key "name".
+// Values are bound by type, much like the database/sql driver does:
+// use [JSON], [Pointer] and [ZeroBlob] to bind those kinds of values.
+//
+// https://sqlite.org/lang_expr.html#varparam
+func (s *Stmt) BindNamed(args map[string]any) error {
+	used := make(map[string]bool, len(args))
+	err := s.bindNamed(func(name string) (param func(int) error, ok bool) {
+		key := name
+		arg, ok := args[key]
+		if !ok {
+			key = name[1:]
+			arg, ok = args[key]
+		}
+		if !ok {
+			return nil, false
+		}
+		used[key] = true
+		return func(param int) error { return s.bind(param, arg) }, true
+	})
+	if err != nil || !s.strict {
+		return err
+	}
+	for key := range args {
+		if !used[key] {
+			return fmt.Errorf("sqlite3: no parameter for argument %q", key)
+		}
+	}
+	return nil
+}
+
+// BindStruct binds the fields of the struct v, or of the struct v points to,
+// to the named parameters of the prepared statement.
+//
+// A parameter named ":name", "@name" or "$name" binds the exported field
+// named "name", case-insensitively, in the field's "sqlite" tag,
+// or by the field's name.
+// Fields tagged "-" are never bound.
+// Fields with the "json" tag option are bound with [Stmt.BindJSON].
+// [time.Time] fields are bound with the [TimeFormat] named
+// by the "time" tag option, or [TimeFormatDefault].
+// See [ScanStruct] for an example.
+//
+// https://sqlite.org/lang_expr.html#varparam
+func (s *Stmt) BindStruct(v any) error {
+	val := reflect.ValueOf(v)
+	if !val.IsValid() {
+		return util.ValueErr
+	}
+	for val.Kind() == reflect.Pointer && !val.IsNil() {
+		val = val.Elem()
+	}
+	fields, err := structFields(val.Type())
+	if err != nil {
+		return err
+	}
+
+	used := make([]bool, len(fields))
+	err = s.bindNamed(func(name string) (param func(int) error, ok bool) {
+		for i := range fields {
+			f := &fields[i]
+			if strings.EqualFold(f.Name, name[1:]) {
+				used[i] = true
+				return func(param int) error { return s.bindField(param, f, val) }, true
+			}
+		}
+		return nil, false
+	})
+	if err != nil || !s.strict {
+		return err
+	}
+	for i, u := range used {
+		if !u {
+			return fmt.Errorf("sqlite3: no parameter for field %q", fields[i].Name)
+		}
+	}
+	return nil
+}
+
+// bindNamed walks every named parameter,
+// and binds it using the binder returned by lookup.
+func (s *Stmt) bindNamed(lookup func(name string) (bind func(param int) error, ok bool)) error {
+	for param := 1; param <= s.BindCount(); param++ {
+		name := s.BindName(param)
+		if name == "" || name[0] == '?' {
+			if s.strict {
+				return fmt.Errorf("sqlite3: parameter %d is not named", param)
+			}
+			continue
+		}
+
+		bind, ok := lookup(name)
+		if !ok {
+			if s.strict {
+				return fmt.Errorf("sqlite3: missing argument for parameter %q", name)
+			}
+			continue
+		}
+		if err := bind(param); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Stmt) bindField(param int, f *util.StructField, v reflect.Value) error {
+	v, err := fieldByIndex(v, f.Index, false)
+	if err != nil {
+		// A nil embedded struct pointer.
+		return s.BindNull(param)
+	}
+
+	if f.JSON {
+		return s.BindJSON(param, v.Interface())
+	}
+	if f.Time != "" {
+		for v.Kind() == reflect.Pointer && !v.IsNil() {
+			v = v.Elem()
+		}
+		if v.Type() == timeType {
+			return s.BindTime(param, v.Interface().(time.Time), TimeFormat(f.Time))
+		}
+	}
+	return s.bind(param, v.Interface())
+}
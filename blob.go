@@ -259,3 +259,116 @@ func (b *Blob) Reopen(row int64) error {
 	b.offset = 0
 	return err
 }
+
+type bindReader struct {
+	r      io.Reader
+	size   int64
+	param  int
+	db     string
+	table  string
+	column string
+}
+
+// BindReader binds a zero-filled BLOB of size bytes to the prepared statement,
+// just like [Stmt.BindZeroBlob], and then, the next time the statement is stepped,
+// writes size bytes read from r into that BLOB, using incremental BLOB I/O.
+// The leftmost SQL parameter has an index of 1.
+//
+// The statement must be an INSERT or an UPDATE that stores the BLOB, unchanged,
+// into column of table in database db, and returns the rowid of the changed row
+// as its first result column, for example:
+//
+//	INSERT INTO files (name, data) VALUES (?, ?) RETURNING rowid
+//	UPDATE files SET data = ? WHERE name = ? RETURNING rowid
+//
+// The BLOB is written into the row returned by the first step,
+// before that step returns.
+// Other rows changed by the statement keep the zero-filled BLOB.
+//
+// In autocommit mode the changed row may be committed
+// before the BLOB is written, and is not rolled back if writing fails.
+// Use an explicit transaction to make both atomic.
+//
+// The reader is only used once:
+// bind it again to reuse the statement.
+//
+// https://sqlite.org/c3ref/blob_open.html
+func (s *Stmt) BindReader(param int, r io.Reader, size int64, db, table, column string) error {
+	err := s.BindZeroBlob(param, size)
+	if err != nil {
+		return err
+	}
+	reader := bindReader{r, size, param, db, table, column}
+	for i := range s.readers {
+		if s.readers[i].param == param {
+			s.readers[i] = reader
+			return nil
+		}
+	}
+	s.readers = append(s.readers, reader)
+	return nil
+}
+
+func (s *Stmt) stepReaders() bool {
+	readers := s.readers
+	s.readers = nil
+	if !s.step() {
+		if s.err == nil {
+			s.err = util.NoBlobErr
+		}
+		return false
+	}
+	if s.ColumnType(0) != INTEGER {
+		s.err = util.NoBlobErr
+		return false
+	}
+
+	rowid := s.ColumnInt64(0)
+	for _, r := range readers {
+		if err := s.c.writeReader(r, rowid); err != nil {
+			s.err = err
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Conn) writeReader(r bindReader, rowid int64) error {
+	blob, err := c.OpenBlob(r.db, r.table, r.column, rowid, true)
+	if err != nil {
+		return err
+	}
+	if blob.Size() != r.size {
+		err = util.NoBlobErr
+	} else {
+		var n int64
+		n, err = blob.ReadFrom(io.LimitReader(r.r, r.size))
+		if err == nil && n < r.size {
+			err = io.ErrUnexpectedEOF
+		}
+	}
+	if e := blob.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// ColumnReader opens a read-only [Blob] to stream a BLOB
+// without loading it into memory.
+// The leftmost column of the result set has the index 0.
+//
+// The result column col must be the rowid of the row that has the BLOB,
+// which is in the column of the table in the database db.
+// Selecting only the rowid, not the BLOB, avoids loading it:
+//
+//	SELECT rowid FROM files WHERE name = ?
+//
+// The caller must close the returned [Blob].
+//
+// https://sqlite.org/c3ref/blob_open.html
+func (s *Stmt) ColumnReader(col int, db, table, column string) (*Blob, error) {
+	if s.ColumnType(col) != INTEGER {
+		return nil, util.NoBlobErr
+	}
+	return s.c.OpenBlob(db, table, column, s.ColumnInt64(col), false)
+}
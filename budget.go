@@ -0,0 +1,90 @@
+package sqlite3
+
+// Budget limits the resources a prepared statement can use
+// each time it runs, from its first [Stmt.Step] until it is done,
+// or until it is reset.
+// Zero fields mean no limit.
+//
+// Exceeding a budget interrupts the statement with an [INTERRUPT_BUDGET] error.
+// The connection remains usable.
+type Budget struct {
+	// Steps limits the number of virtual machine steps,
+	// as counted by [STMTSTATUS_VM_STEP].
+	// It's checked by the progress handler every 100 steps or so.
+	Steps int
+	// Rows limits the number of rows returned.
+	Rows int
+	// Memory limits the growth, in bytes, of the heap memory used by
+	// the statement and the page cache of the connection,
+	// as reported by [STMTSTATUS_MEMUSED] and [DBSTATUS_CACHE_USED].
+	// It's checked by the progress handler every 100 steps or so.
+	Memory int64
+}
+
+type budget struct {
+	Budget
+	err     error
+	running bool
+	rows    int
+	steps   int
+	memory  int64
+}
+
+// SetBudget sets limits on the resources the prepared statement can use.
+// A zero Budget removes all limits.
+func (s *Stmt) SetBudget(b Budget) {
+	if b == (Budget{}) {
+		s.budget = nil
+	} else {
+		s.budget = &budget{Budget: b}
+	}
+}
+
+func (s *Stmt) memoryUsed() int64 {
+	cache, _, _ := s.c.Status(DBSTATUS_CACHE_USED, false)
+	return int64(cache) + int64(s.Status(STMTSTATUS_MEMUSED, false))
+}
+
+func (b *budget) start(s *Stmt) {
+	if !b.running {
+		b.running = true
+		b.rows = 0
+		b.steps = s.Status(STMTSTATUS_VM_STEP, false)
+		b.memory = s.memoryUsed()
+	}
+}
+
+func (b *budget) stop() {
+	b.running = false
+	b.err = nil
+}
+
+func (b *budget) row() bool {
+	b.rows++
+	if b.Rows > 0 && b.rows > b.Rows {
+		b.exceeded("rows")
+		return false
+	}
+	return true
+}
+
+// check is called by the progress handler.
+func (b *budget) check(s *Stmt) bool {
+	if b.err == nil {
+		if b.Steps > 0 && s.Status(STMTSTATUS_VM_STEP, false)-b.steps > b.Steps {
+			b.exceeded("steps")
+		}
+		if b.Memory > 0 && s.memoryUsed()-b.memory > b.Memory {
+			b.exceeded("memory")
+		}
+	}
+	return b.err == nil
+}
+
+func (b *budget) exceeded(limit string) {
+	b.err = &Error{
+		code: res_t(INTERRUPT_BUDGET),
+		str:  "interrupted",
+		msg:  "statement exceeded its " + limit + " budget",
+	}
+}
@@ -135,6 +135,18 @@ func (c *Conn) FileControl(schema string, op FcntlOpcode, arg ...any) (any, erro
 			stk_t(op), stk_t(ptr)))
 		ret = int(util.Read32[int32](c.mod, ptr))
 
+	case FCNTL_SIZE_LIMIT:
+		limit := -1
+		if len(arg) > 0 {
+			limit = arg[0].(int)
+		}
+		ptr := c.arena.new(8)
+		util.Write64(c.mod, ptr, int64(limit))
+		rc = res_t(c.call("sqlite3_file_control",
+			stk_t(c.handle), stk_t(schemaPtr),
+			stk_t(op), stk_t(ptr)))
+		ret = int(util.Read64[int64](c.mod, ptr))
+
 	case FCNTL_DATA_VERSION:
 		rc = res_t(c.call("sqlite3_file_control",
 			stk_t(c.handle), stk_t(schemaPtr),
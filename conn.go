@@ -25,14 +25,17 @@ type Conn struct {
 
 	interrupt  context.Context
 	pending    *Stmt
+	budgeted   *Stmt
 	stmts      []*Stmt
 	busy       func(context.Context, int) bool
+	progress   func() bool
 	log        func(xErrorCode, string)
 	collation  func(*Conn, string)
 	wal        func(*Conn, string, int) error
 	trace      func(TraceEvent, any, any) error
 	authorizer func(AuthorizerActionCode, string, string, string, string) AuthorizerReturnCode
 	update     func(AuthorizerActionCode, string, string, int64)
+	preupdate  func(PreUpdate, AuthorizerActionCode, string, string, int64, int64)
 	commit     func() bool
 	rollback   func()
 
@@ -40,6 +43,8 @@ type Conn struct {
 	busylst time.Time
 	arena   arena
 	handle  ptr_t
+	progN   int
+	progCnt int
 }
 
 // Open calls [OpenFlags] with [OPEN_READWRITE], [OPEN_CREATE] and [OPEN_URI].
@@ -218,6 +223,7 @@ func (c *Conn) PrepareFlags(sql string, flags PrepareFlag) (stmt *Stmt, tail str
 	if sql := sql[util.Read32[ptr_t](c.mod, tailPtr)-sqlPtr:]; sql != "" {
 		tail = sql
 	}
+	stmt.sql = sql[:len(sql)-len(tail)]
 
 	if err := c.error(rc, sql); err != nil {
 		return nil, "", err
@@ -370,6 +376,32 @@ func (c *Conn) checkInterrupt(handle ptr_t) {
 	}
 }
 
+// ProgressHandler registers a callback that is invoked periodically
+// during long running calls, about every nOps virtual machine instructions.
+// If the callback returns true, the operation is interrupted,
+// and the call returns [INTERRUPT].
+//
+// The progress handler works together with [Conn.SetInterrupt].
+//
+// https://sqlite.org/c3ref/progress_handler.html
+func (c *Conn) ProgressHandler(nOps int, cb func() (interrupt bool)) {
+	// SQLite allows a single progress handler,
+	// which is also used to check for interrupts.
+	// Call it at least every 100 instructions,
+	// and invoke cb every progN calls.
+	ops := 100
+	if cb == nil || nOps <= 0 {
+		cb = nil
+		c.progN = 0
+	} else {
+		c.progN = (nOps + ops - 1) / ops
+		ops = (nOps + c.progN/2) / c.progN
+	}
+	c.progress = cb
+	c.progCnt = 0
+	c.call("sqlite3_progress_handler_go", stk_t(c.handle), stk_t(ops))
+}
+
 func progressCallback(ctx context.Context, mod api.Module, _ ptr_t) (interrupt int32) {
 	if c, ok := ctx.Value(connKey{}).(*Conn); ok {
 		if c.interrupt.Done() != nil {
@@ -378,6 +410,17 @@ func progressCallback(ctx context.Context, mod api.Module, _ ptr_t) (interrupt i
 		if c.interrupt.Err() != nil {
 			interrupt = 1
 		}
+		if s := c.budgeted; s != nil && interrupt == 0 && !s.budget.check(s) {
+			interrupt = 1
+		}
+		if c.progress != nil && interrupt == 0 {
+			if c.progCnt++; c.progCnt >= c.progN {
+				c.progCnt = 0
+				if c.progress() {
+					interrupt = 1
+				}
+			}
+		}
 	}
 	return interrupt
 }
@@ -503,6 +546,30 @@ func (c *Conn) error(rc res_t, sql ...string) error {
 	return c.sqlite.error(rc, c.handle, sql...)
 }
 
+func (c *Conn) queryIter(sql string, args []any, yield func(*Stmt, error) bool) {
+	stmt, _, err := c.Prepare(sql)
+	if err != nil {
+		yield(nil, err)
+		return
+	}
+	defer stmt.Close()
+
+	for i, arg := range args {
+		if err := stmt.bind(i+1, arg); err != nil {
+			yield(nil, err)
+			return
+		}
+	}
+	for stmt.Step() {
+		if !yield(stmt, nil) {
+			return
+		}
+	}
+	if err := stmt.Reset(); err != nil {
+		yield(nil, err)
+	}
+}
+
 func (c *Conn) stmtsIter(yield func(*Stmt) bool) {
 	for _, s := range c.stmts {
 		if !yield(s) {
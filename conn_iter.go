@@ -9,3 +9,24 @@ import "iter"
 //
 // https://sqlite.org/c3ref/next_stmt.html
 func (c *Conn) Stmts() iter.Seq[*Stmt] { return c.stmtsIter }
+
+// Query prepares an SQL statement, binds args to its parameters,
+// and returns an iterator over its result rows.
+// Only the first statement in sql is run.
+// Args are bound by their type, much like the database/sql driver does.
+//
+// The statement is closed when the iteration stops.
+// It must not be used after the iteration, or concurrently with it.
+//
+//	for stmt, err := range db.Query(`SELECT id, name FROM users WHERE age > ?`, 18) {
+//		if err != nil {
+//			return err
+//		}
+//		id, name := stmt.ColumnInt(0), stmt.ColumnText(1)
+//		// ...
+//	}
+func (c *Conn) Query(sql string, args ...any) iter.Seq2[*Stmt, error] {
+	return func(yield func(*Stmt, error) bool) {
+		c.queryIter(sql, args, yield)
+	}
+}
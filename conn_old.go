@@ -7,3 +7,16 @@ package sqlite3
 //
 // https://sqlite.org/c3ref/next_stmt.html
 func (c *Conn) Stmts() func(func(*Stmt) bool) { return c.stmtsIter }
+
+// Query prepares an SQL statement, binds args to its parameters,
+// and returns an iterator over its result rows.
+// Only the first statement in sql is run.
+// Args are bound by their type, much like the database/sql driver does.
+//
+// The statement is closed when the iteration stops.
+// It must not be used after the iteration, or concurrently with it.
+func (c *Conn) Query(sql string, args ...any) func(func(*Stmt, error) bool) {
+	return func(yield func(*Stmt, error) bool) {
+		c.queryIter(sql, args, yield)
+	}
+}
@@ -146,6 +146,10 @@ const (
 	NOTICE_RBU              ExtendedErrorCode = xErrorCode(NOTICE) | (3 << 8)
 	WARNING_AUTOINDEX       ExtendedErrorCode = xErrorCode(WARNING) | (1 << 8)
 	AUTH_USER               ExtendedErrorCode = xErrorCode(AUTH) | (1 << 8)
+
+	// INTERRUPT_BUDGET is not an SQLite result code.
+	// It's returned when a statement exceeds its [Budget].
+	INTERRUPT_BUDGET ExtendedErrorCode = xErrorCode(INTERRUPT) | (1 << 8)
 )
 
 // OpenFlag is a flag for the [OpenFlags] function.
@@ -179,6 +183,26 @@ const (
 	PREPARE_DONT_LOG   PrepareFlag = 0x10
 )
 
+// SerializeFlag is a flag that can be passed to [Conn.Serialize].
+//
+// https://sqlite.org/c3ref/c_serialize_nocopy.html
+type SerializeFlag uint32
+
+const (
+	SERIALIZE_NOCOPY SerializeFlag = 0x001
+)
+
+// DeserializeFlag is a flag that can be passed to [Conn.Deserialize].
+//
+// https://sqlite.org/c3ref/c_deserialize_freeonclose.html
+type DeserializeFlag uint32
+
+const (
+	// DESERIALIZE_FREEONCLOSE DeserializeFlag = 1
+	DESERIALIZE_RESIZEABLE DeserializeFlag = 2
+	DESERIALIZE_READONLY   DeserializeFlag = 4
+)
+
 // FunctionFlag is a flag that can be passed to
 // [Conn.CreateFunction] and [Conn.CreateWindowFunction].
 //
@@ -211,6 +235,18 @@ const (
 	STMTSTATUS_MEMUSED       StmtStatus = 99
 )
 
+// ExplainMode is the explain setting of a prepared statement,
+// used by the [Stmt.Explain] method.
+//
+// https://sqlite.org/c3ref/stmt_explain.html
+type ExplainMode uint8
+
+const (
+	EXPLAIN_NONE       ExplainMode = 0
+	EXPLAIN            ExplainMode = 1
+	EXPLAIN_QUERY_PLAN ExplainMode = 2
+)
+
 // DBStatus are the available "verbs" that can be passed to the [Conn.Status] method.
 //
 // https://sqlite.org/c3ref/c_dbstatus_options.html
@@ -279,6 +315,7 @@ const (
 	FCNTL_VFS_POINTER         FcntlOpcode = 27
 	FCNTL_JOURNAL_POINTER     FcntlOpcode = 28
 	FCNTL_DATA_VERSION        FcntlOpcode = 35
+	FCNTL_SIZE_LIMIT          FcntlOpcode = 36
 	FCNTL_RESERVE_BYTES       FcntlOpcode = 38
 	FCNTL_RESET_CACHE         FcntlOpcode = 42
 )
@@ -394,6 +431,32 @@ const (
 	TRACE_CLOSE   TraceEvent = 0x08
 )
 
+// ConflictType are the types of conflict that may be passed
+// to the conflict handler of [Conn.ApplyChangeset].
+//
+// https://sqlite.org/session/c_changeset_conflict.html
+type ConflictType uint32
+
+const (
+	CHANGESET_DATA        ConflictType = 1
+	CHANGESET_NOTFOUND    ConflictType = 2
+	CHANGESET_CONFLICT    ConflictType = 3
+	CHANGESET_CONSTRAINT  ConflictType = 4
+	CHANGESET_FOREIGN_KEY ConflictType = 5
+)
+
+// ConflictAction are the allowed return values
+// from the conflict handler of [Conn.ApplyChangeset].
+//
+// https://sqlite.org/session/c_changeset_abort.html
+type ConflictAction uint32
+
+const (
+	CHANGESET_OMIT    ConflictAction = 0
+	CHANGESET_REPLACE ConflictAction = 1
+	CHANGESET_ABORT   ConflictAction = 2
+)
+
 // Datatype is a fundamental datatype of SQLite.
 //
 // https://sqlite.org/c3ref/c_blob.html
@@ -583,6 +583,13 @@ type rows struct {
 	types []string
 	nulls []bool
 	scans []scantype
+
+	// Rows are fetched in batches,
+	// and served from the batch by Next.
+	batch sqlite3.Batch
+	next  int  // Index of the next row in the batch.
+	size  int  // Number of rows to fetch in the next batch.
+	done  bool // The statement is done, or failed.
 }
 
 type scantype byte
@@ -605,6 +612,9 @@ var (
 )
 
 func (r *rows) Close() error {
+	r.batch = sqlite3.Batch{}
+	r.next = 0
+	r.done = true
 	return errors.Join(
 		r.Stmt.Reset(),
 		r.Stmt.ClearBindings())
@@ -696,19 +706,20 @@ func (r *rows) ColumnTypeScanType(index int) (typ reflect.Type) {
 	r.loadColumnMetadata()
 	scan := r.scans[index]
 
-	if r.Stmt.Busy() {
+	if r.next > 0 {
 		// SQLite is dynamically typed and we now have a row.
 		// Always use the type of the value itself,
 		// unless the scan type is more specific
 		// and can scan the actual value.
-		val := scantype(r.Stmt.ColumnType(index))
+		v := r.batch.Row(r.next - 1)[index]
+		val := valueType(v)
 		useValType := true
 		switch {
 		case scan == _TIME && val != _BLOB && val != _NULL:
-			t := r.Stmt.ColumnTime(index, r.tmRead)
-			useValType = t == time.Time{}
+			t, err := r.tmRead.Decode(v)
+			useValType = err != nil || t == time.Time{}
 		case scan == _BOOL && val == _INT:
-			i := r.Stmt.ColumnInt64(index)
+			i := v.(int64)
 			useValType = i != 0 && i != 1
 		case scan == _BLOB && val == _NULL:
 			useValType = false
@@ -736,19 +747,34 @@ func (r *rows) ColumnTypeScanType(index int) (typ reflect.Type) {
 	}
 }
 
-func (r *rows) Next(dest []driver.Value) error {
-	old := r.Stmt.Conn().SetInterrupt(r.ctx)
-	defer r.Stmt.Conn().SetInterrupt(old)
+func valueType(v any) scantype {
+	switch v.(type) {
+	case int64:
+		return _INT
+	case float64:
+		return _REAL
+	case string:
+		return _TEXT
+	case []byte:
+		return _BLOB
+	default:
+		return _NULL
+	}
+}
 
-	if !r.Stmt.Step() {
-		if err := r.Stmt.Err(); err != nil {
-			return err
+func (r *rows) Next(dest []driver.Value) error {
+	if r.next >= r.batch.Len() {
+		if r.done || !r.fetch() {
+			if err := r.Stmt.Err(); err != nil {
+				return err
+			}
+			return io.EOF
 		}
-		return io.EOF
 	}
 
 	data := unsafe.Slice((*any)(unsafe.SliceData(dest)), len(dest))
-	err := r.Stmt.Columns(data...)
+	err := r.batch.Columns(r.next, data...)
+	r.next++
 	for i := range dest {
 		if t, ok := r.decodeTime(i, dest[i]); ok {
 			dest[i] = t
@@ -757,6 +783,22 @@ func (r *rows) Next(dest []driver.Value) error {
 	return err
 }
 
+// fetch fetches the next batch of rows,
+// and reports if it has any.
+//
+// The first batch has a single row, so that queries
+// that read a single row don't step any further;
+// the size of each batch doubles after that.
+func (r *rows) fetch() bool {
+	old := r.Stmt.Conn().SetInterrupt(r.ctx)
+	defer r.Stmt.Conn().SetInterrupt(old)
+
+	r.size = min(max(2*r.size, 1), 256)
+	r.done = !r.Stmt.StepBatch(r.size, &r.batch)
+	r.next = 0
+	return r.batch.Len() > 0
+}
+
 func (r *rows) decodeTime(i int, v any) (_ time.Time, ok bool) {
 	switch v := v.(type) {
 	case int64, float64:
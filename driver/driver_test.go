@@ -467,3 +467,80 @@ func Test_ColumnType_ScanType(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func Test_Rows_readAhead(t *testing.T) {
+	t.Parallel()
+	tmp := memdb.TestDB(t)
+
+	db, err := sql.Open("sqlite3", tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	stmt, err := db.Prepare(`
+		WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x+1 FROM c WHERE x < ?)
+		SELECT x, CASE WHEN x = ? THEN abs(-9223372036854775808) ELSE x END FROM c`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+
+	// Reads the rows of a query, stopping after limit rows.
+	query := func(count, fail, limit int) (int, error) {
+		rows, err := stmt.Query(count, fail)
+		if err != nil {
+			return 0, err
+		}
+		defer rows.Close()
+
+		var n int
+		for n < limit && rows.Next() {
+			var x, y int
+			err := rows.Scan(&x, &y)
+			if err != nil {
+				return n, err
+			}
+			if n++; x != n || y != n {
+				t.Errorf("got (%d, %d), want %d", x, y, n)
+			}
+		}
+		return n, rows.Err()
+	}
+
+	// Rows span several batches.
+	n, err := query(1000, 0, math.MaxInt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1000 {
+		t.Errorf("got %d rows, want 1000", n)
+	}
+
+	// Rows read before an error are returned.
+	n, err = query(1000, 600, math.MaxInt)
+	if !errors.Is(err, sqlite3.ERROR) {
+		t.Errorf("got %v, want sqlite3.ERROR", err)
+	}
+	if n != 599 {
+		t.Errorf("got %d rows, want 599", n)
+	}
+
+	// Closing rows discards rows read ahead.
+	n, err = query(1000, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 10 {
+		t.Errorf("got %d rows, want 10", n)
+	}
+
+	// The statement is reused from the start.
+	n, err = query(20, 0, math.MaxInt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 20 {
+		t.Errorf("got %d rows, want 20", n)
+	}
+}
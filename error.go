@@ -15,7 +15,9 @@ type Error struct {
 	str  string
 	msg  string
 	sql  string
+	off  int
 	code res_t
+	pos  bool // off is known
 }
 
 // Code returns the primary error code for this error.
@@ -96,6 +98,61 @@ func (e *Error) SQL() string {
 	return e.sql
 }
 
+// Offset returns the byte offset, into the SQL that was being prepared,
+// of the token that triggered a syntax error, or -1 if not known.
+//
+// https://sqlite.org/c3ref/errcode.html
+func (e *Error) Offset() int {
+	if !e.pos {
+		return -1
+	}
+	return e.off
+}
+
+// Constraint describes the constraint that failed for a [CONSTRAINT] error.
+type Constraint struct {
+	Kind    ExtendedErrorCode // e.g. CONSTRAINT_UNIQUE
+	Table   string            // the table, if known
+	Columns []string          // the columns, if known
+	Name    string            // the CHECK constraint or index name, if known
+}
+
+// Constraint returns a description of the failed constraint,
+// parsed from the error message,
+// or nil if this is not a [CONSTRAINT] error.
+func (e *Error) Constraint() *Constraint {
+	if e.Code() != CONSTRAINT {
+		return nil
+	}
+
+	c := Constraint{Kind: e.ExtendedCode()}
+	kind, detail, ok := strings.Cut(e.msg, " constraint failed: ")
+	switch {
+	case ok && (kind == "UNIQUE" || kind == "NOT NULL"):
+		// table.column, table.column, ...
+		// index 'name'
+		if name, ok := strings.CutPrefix(detail, "index '"); ok {
+			c.Name = strings.TrimSuffix(name, "'")
+			break
+		}
+		for _, col := range strings.Split(detail, ", ") {
+			table, column, _ := strings.Cut(col, ".")
+			c.Table = table
+			c.Columns = append(c.Columns, column)
+		}
+	case ok && kind == "CHECK":
+		c.Name = detail
+	case c.Kind == CONSTRAINT_DATATYPE:
+		// cannot store TYPE value in TYPE column table.column
+		if _, col, ok := strings.Cut(e.msg, " column "); ok {
+			table, column, _ := strings.Cut(col, ".")
+			c.Table = table
+			c.Columns = []string{column}
+		}
+	}
+	return &c
+}
+
 // Error implements the error interface.
 func (e ErrorCode) Error() string {
 	return util.ErrorCodeString(uint32(e))
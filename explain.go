@@ -0,0 +1,162 @@
+package sqlite3
+
+import "github.com/ncruces/go-sqlite3/internal/util"
+
+// QueryPlan is a node in the EXPLAIN QUERY PLAN tree of a prepared statement.
+//
+// https://sqlite.org/eqp.html
+type QueryPlan struct {
+	ID       int
+	Detail   string
+	Children []*QueryPlan
+}
+
+// ScanStatus is a node in the query plan of a prepared statement,
+// with the performance counters collected while running it.
+// Loops and Rows are negative for nodes that are not loops.
+//
+// https://sqlite.org/c3ref/stmt_scanstatus.html
+type ScanStatus struct {
+	Explain  string  // The EXPLAIN QUERY PLAN text for this node.
+	Name     string  // The name of the table or index used by a loop.
+	Loops    int64   // The number of times the loop has run.
+	Rows     int64   // The number of rows visited by the loop.
+	Estimate float64 // The estimated number of rows output per loop.
+	Cycles   int64   // The number of cycles spent running this node.
+	Children []*ScanStatus
+}
+
+const (
+	_SCANSTAT_NLOOP    = 0
+	_SCANSTAT_NVISIT   = 1
+	_SCANSTAT_EST      = 2
+	_SCANSTAT_NAME     = 3
+	_SCANSTAT_EXPLAIN  = 4
+	_SCANSTAT_SELECTID = 5
+	_SCANSTAT_PARENTID = 6
+	_SCANSTAT_NCYCLE   = 7
+
+	_SCANSTAT_COMPLEX = 1
+)
+
+// Explain changes the explain setting of the prepared statement.
+// The statement must be reset.
+//
+// With [EXPLAIN_QUERY_PLAN], Explain also runs the statement,
+// and returns its EXPLAIN QUERY PLAN tree.
+// The statement keeps the new setting until Explain is called again.
+//
+// https://sqlite.org/c3ref/stmt_explain.html
+func (s *Stmt) Explain(mode ExplainMode) ([]*QueryPlan, error) {
+	if err := s.c.needfn("sqlite3_stmt_explain"); err != nil {
+		return nil, err
+	}
+	rc := res_t(s.c.call("sqlite3_stmt_explain", stk_t(s.handle), stk_t(mode)))
+	if err := s.c.error(rc); err != nil || mode != EXPLAIN_QUERY_PLAN {
+		return nil, err
+	}
+	return s.queryPlan()
+}
+
+// queryPlan runs an EXPLAIN QUERY PLAN statement,
+// and builds a tree from the rows it returns:
+// each row has the ID of a node, and the ID of its parent.
+func (s *Stmt) queryPlan() ([]*QueryPlan, error) {
+	var roots []*QueryPlan
+	nodes := map[int]*QueryPlan{}
+	for s.Step() {
+		node := &QueryPlan{
+			ID:     s.ColumnInt(0),
+			Detail: s.ColumnText(3),
+		}
+		nodes[node.ID] = node
+		if parent := nodes[s.ColumnInt(1)]; parent != nil {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+	if err := s.Reset(); err != nil {
+		return nil, err
+	}
+	return roots, nil
+}
+
+// IsExplain returns the explain setting of the prepared statement.
+//
+// https://sqlite.org/c3ref/stmt_isexplain.html
+func (s *Stmt) IsExplain() (ExplainMode, error) {
+	if err := s.c.needfn("sqlite3_stmt_isexplain"); err != nil {
+		return 0, err
+	}
+	mode := int32(s.c.call("sqlite3_stmt_isexplain", stk_t(s.handle)))
+	return ExplainMode(mode), nil
+}
+
+// ScanStatus returns the query plan of the prepared statement,
+// with the performance counters collected since it was prepared,
+// or since the last call to [Stmt.ScanStatusReset].
+//
+// https://sqlite.org/c3ref/stmt_scanstatus.html
+func (s *Stmt) ScanStatus() ([]*ScanStatus, error) {
+	if err := s.c.needfn("sqlite3_stmt_scanstatus_v2"); err != nil {
+		return nil, err
+	}
+
+	defer s.c.arena.mark()()
+	ptr := s.c.arena.new(8)
+
+	scan := func(idx, op int32) bool {
+		rc := res_t(s.c.call("sqlite3_stmt_scanstatus_v2", stk_t(s.handle),
+			stk_t(idx), stk_t(op), _SCANSTAT_COMPLEX, stk_t(ptr)))
+		return rc == _OK
+	}
+	scanString := func(idx, op int32) string {
+		if scan(idx, op) {
+			if str := util.Read32[ptr_t](s.c.mod, ptr); str != 0 {
+				return util.ReadString(s.c.mod, str, _MAX_SQL_LENGTH)
+			}
+		}
+		return ""
+	}
+
+	var roots []*ScanStatus
+	nodes := map[int32]*ScanStatus{}
+	for idx := int32(0); scan(idx, _SCANSTAT_SELECTID); idx++ {
+		id := util.Read32[int32](s.c.mod, ptr)
+
+		var node ScanStatus
+		node.Explain = scanString(idx, _SCANSTAT_EXPLAIN)
+		node.Name = scanString(idx, _SCANSTAT_NAME)
+		scan(idx, _SCANSTAT_NLOOP)
+		node.Loops = util.Read64[int64](s.c.mod, ptr)
+		scan(idx, _SCANSTAT_NVISIT)
+		node.Rows = util.Read64[int64](s.c.mod, ptr)
+		scan(idx, _SCANSTAT_EST)
+		node.Estimate = util.ReadFloat64(s.c.mod, ptr)
+		scan(idx, _SCANSTAT_NCYCLE)
+		node.Cycles = util.Read64[int64](s.c.mod, ptr)
+		scan(idx, _SCANSTAT_PARENTID)
+		parent := util.Read32[int32](s.c.mod, ptr)
+
+		nodes[id] = &node
+		if p := nodes[parent]; p != nil {
+			p.Children = append(p.Children, &node)
+		} else {
+			roots = append(roots, &node)
+		}
+	}
+	return roots, nil
+}
+
+// ScanStatusReset zeroes the performance counters
+// reported by [Stmt.ScanStatus].
+//
+// https://sqlite.org/c3ref/stmt_scanstatus_reset.html
+func (s *Stmt) ScanStatusReset() error {
+	if err := s.c.needfn("sqlite3_stmt_scanstatus_reset"); err != nil {
+		return err
+	}
+	s.c.call("sqlite3_stmt_scanstatus_reset", stk_t(s.handle))
+	return nil
+}
@@ -0,0 +1,63 @@
+package sqlite3
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStmt_queryPlan(t *testing.T) {
+	t.Parallel()
+
+	db, err := Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = db.Exec(`
+		CREATE TABLE t (a);
+		CREATE TABLE u (b);
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stmt, _, err := db.Prepare(`EXPLAIN QUERY PLAN
+		SELECT * FROM t WHERE a IN (SELECT b FROM u WHERE b IN (SELECT a FROM t)) ORDER BY a`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+
+	var buf strings.Builder
+	var format func(indent string, plan []*QueryPlan)
+	format = func(indent string, plan []*QueryPlan) {
+		for _, node := range plan {
+			buf.WriteString(indent + node.Detail + "\n")
+			format(indent+"  ", node.Children)
+		}
+	}
+
+	const want = `SCAN t
+LIST SUBQUERY 2
+  SCAN u
+  LIST SUBQUERY 1
+    SCAN t
+    CREATE BLOOM FILTER
+  CREATE BLOOM FILTER
+USE TEMP B-TREE FOR ORDER BY
+`
+
+	// The statement is reset, and can be explained again.
+	for range 2 {
+		plan, err := stmt.queryPlan()
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf.Reset()
+		format("", plan)
+		if got := buf.String(); got != want {
+			t.Errorf("got:\n%s\nwant:\n%s", got, want)
+		}
+	}
+}
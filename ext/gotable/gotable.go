@@ -0,0 +1,312 @@
+//go:build go1.23
+
+// Package gotable turns Go iterators and slices
+// into table-valued SQL functions.
+//
+// The columns of the table are the exported fields of a struct,
+// named by their "sqlite" tag, or by the field's name.
+// Fields tagged "-" are skipped,
+// and fields with the "json" tag option are returned as JSON:
+//
+//	type Planet struct {
+//		Name  string   `sqlite:"name"`
+//		Mass  float64  `sqlite:"mass"`
+//		Moons []string `sqlite:"moons,json"`
+//	}
+//
+// Any other type is returned in a single column named value.
+//
+// https://sqlite.org/vtab.html#tabfunc2
+package gotable
+
+import (
+	"fmt"
+	"iter"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ncruces/go-sqlite3"
+	"github.com/ncruces/go-sqlite3/internal/util"
+)
+
+// Register registers an eponymous table-valued SQL function
+// that returns the values produced by the iterator returned by fn.
+//
+// Each of params declares a hidden column that can be used
+// as an argument to the function, or constrained for equality,
+// and that returns that argument.
+// Arguments are passed to fn in order: an argument can only be omitted
+// if all the arguments that follow it are also omitted.
+// Args are copies that stay valid until the iterator is stopped,
+// so the iterator can use them.
+func Register[T any](db *sqlite3.Conn, name string, fn func(arg ...sqlite3.Value) iter.Seq[T], params ...string) error {
+	cols, err := columns(reflect.TypeFor[T]())
+	if err != nil {
+		return err
+	}
+
+	var sql strings.Builder
+	sql.WriteString(`CREATE TABLE x(`)
+	for i, col := range cols {
+		if i > 0 {
+			sql.WriteByte(',')
+		}
+		sql.WriteString(sqlite3.QuoteIdentifier(col.name))
+		sql.WriteString(col.decl)
+	}
+	for _, param := range params {
+		sql.WriteByte(',')
+		sql.WriteString(sqlite3.QuoteIdentifier(param))
+		sql.WriteString(" HIDDEN")
+	}
+	sql.WriteByte(')')
+
+	return sqlite3.CreateModule(db, name, nil,
+		func(db *sqlite3.Conn, _, _, _ string, _ ...string) (*table[T], error) {
+			err := db.DeclareVTab(sql.String())
+			return &table[T]{fn: fn, cols: cols, params: len(params)}, err
+		})
+}
+
+// RegisterSlice registers an eponymous table-valued SQL function
+// that returns the elements of rows.
+func RegisterSlice[T any](db *sqlite3.Conn, name string, rows []T) error {
+	return Register(db, name, func(...sqlite3.Value) iter.Seq[T] {
+		return slices.Values(rows)
+	})
+}
+
+type column struct {
+	name  string
+	decl  string
+	index []int
+	json  bool
+}
+
+func columns(typ reflect.Type) ([]column, error) {
+	struc := typ
+	if struc.Kind() == reflect.Pointer {
+		struc = struc.Elem()
+	}
+	if struc.Kind() != reflect.Struct || struc == reflect.TypeFor[time.Time]() {
+		return []column{{name: "value", decl: declType(typ)}}, nil
+	}
+
+	var cols []column
+	for _, f := range util.StructFields(struc) {
+		col := column{name: f.Name, index: f.Index, json: f.JSON}
+		if !col.json {
+			col.decl = declType(struc.FieldByIndex(f.Index).Type)
+		}
+		cols = append(cols, col)
+	}
+	if cols == nil {
+		return nil, fmt.Errorf("gotable: no columns in %v", typ)
+	}
+	return cols, nil
+}
+
+func declType(typ reflect.Type) string {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Bool:
+		return " INTEGER"
+	case reflect.Float32, reflect.Float64:
+		return " REAL"
+	case reflect.String:
+		return " TEXT"
+	case reflect.Slice:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return " BLOB"
+		}
+	}
+	return ""
+}
+
+type table[T any] struct {
+	fn     func(arg ...sqlite3.Value) iter.Seq[T]
+	cols   []column
+	params int
+}
+
+func (t *table[T]) BestIndex(idx *sqlite3.IndexInfo) error {
+	// Pass equality constraints on hidden columns as arguments.
+	// Each argument is passed once: SQLite checks any duplicates.
+	// idxNum is the number of arguments.
+	used := make([]bool, t.params)
+	unusable := make([]bool, t.params)
+	for i, cst := range idx.Constraint {
+		p := cst.Column - len(t.cols)
+		if p < 0 || cst.Op != sqlite3.INDEX_CONSTRAINT_EQ {
+			continue
+		}
+		if !cst.Usable {
+			unusable[p] = true
+			continue
+		}
+		if used[p] {
+			continue
+		}
+		used[p] = true
+		idx.ConstraintUsage[i] = sqlite3.IndexConstraintUsage{
+			Omit:      true,
+			ArgvIndex: p + 1,
+		}
+	}
+	for p := range used {
+		if unusable[p] && !used[p] {
+			return sqlite3.CONSTRAINT
+		}
+	}
+
+	args := 0
+	for _, u := range used {
+		if !u {
+			break
+		}
+		args++
+	}
+	if slices.Contains(used[args:], true) {
+		return sqlite3.CONSTRAINT
+	}
+	idx.IdxNum = args
+	idx.EstimatedCost = 1e6 / float64(1+args)
+	return nil
+}
+
+func (t *table[T]) Open() (sqlite3.VTabCursor, error) {
+	return &cursor[T]{table: t}, nil
+}
+
+type cursor[T any] struct {
+	table *table[T]
+	next  func() (T, bool)
+	stop  func()
+	args  []*sqlite3.Value
+	row   reflect.Value
+	rowID int64
+	eof   bool
+}
+
+func (c *cursor[T]) Close() error {
+	if c.stop != nil {
+		c.stop()
+		c.stop = nil
+	}
+	for _, a := range c.args {
+		a.Close()
+	}
+	c.args = c.args[:0]
+	return nil
+}
+
+func (c *cursor[T]) Filter(idxNum int, idxStr string, arg ...sqlite3.Value) error {
+	c.Close()
+	args := make([]sqlite3.Value, len(arg))
+	for i, a := range arg {
+		c.args = append(c.args, a.Dup())
+		args[i] = *c.args[i]
+	}
+	c.next, c.stop = iter.Pull(c.table.fn(args...))
+	c.rowID = 0
+	return c.Next()
+}
+
+func (c *cursor[T]) Next() error {
+	val, ok := c.next()
+	c.row = reflect.ValueOf(&val).Elem()
+	c.eof = !ok
+	c.rowID++
+	return nil
+}
+
+func (c *cursor[T]) EOF() bool {
+	return c.eof
+}
+
+func (c *cursor[T]) RowID() (int64, error) {
+	return c.rowID, nil
+}
+
+func (c *cursor[T]) Column(ctx sqlite3.Context, n int) error {
+	if n >= len(c.table.cols) {
+		// Hidden columns return their arguments.
+		if p := n - len(c.table.cols); p < len(c.args) {
+			ctx.ResultValue(*c.args[p])
+		}
+		return nil
+	}
+
+	col := &c.table.cols[n]
+	v := c.row
+	if col.index != nil {
+		if v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				ctx.ResultNull()
+				return nil
+			}
+			v = v.Elem()
+		}
+		f, err := v.FieldByIndexErr(col.index)
+		if err != nil {
+			// A nil embedded struct pointer.
+			ctx.ResultNull()
+			return nil
+		}
+		v = f
+	}
+
+	if col.json {
+		ctx.ResultJSON(v.Interface())
+		return nil
+	}
+	return result(ctx, v)
+}
+
+func result(ctx sqlite3.Context, v reflect.Value) error {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			ctx.ResultNull()
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	k := v.Kind()
+	switch {
+	case v.Type() == reflect.TypeFor[time.Time]():
+		ctx.ResultTime(v.Interface().(time.Time), sqlite3.TimeFormatDefault)
+
+	case v.CanInt():
+		ctx.ResultInt64(v.Int())
+
+	case v.CanUint():
+		i64 := int64(v.Uint())
+		if i64 < 0 {
+			return fmt.Errorf("gotable: integer overflow:%.0w %d", sqlite3.MISMATCH, v.Uint())
+		}
+		ctx.ResultInt64(i64)
+
+	case v.CanFloat():
+		ctx.ResultFloat(v.Float())
+
+	case k == reflect.Bool:
+		ctx.ResultBool(v.Bool())
+
+	case k == reflect.String:
+		ctx.ResultText(v.String())
+
+	case k == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		ctx.ResultBlob(v.Bytes())
+
+	default:
+		return fmt.Errorf("gotable: unsupported value:%.0w %v", sqlite3.MISMATCH, util.ReflectType(v))
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+//go:build go1.23
+
+package gotable_test
+
+import (
+	"iter"
+	"testing"
+
+	"github.com/ncruces/go-sqlite3"
+	_ "github.com/ncruces/go-sqlite3/embed"
+	"github.com/ncruces/go-sqlite3/ext/gotable"
+	_ "github.com/ncruces/go-sqlite3/internal/testcfg"
+)
+
+type planet struct {
+	Name   string   `sqlite:"name"`
+	Mass   float64  `sqlite:"mass"`
+	Moons  []string `sqlite:"moons,json"`
+	Secret string   `sqlite:"-"`
+}
+
+var planets = []planet{
+	{Name: "Mercury", Mass: 0.055},
+	{Name: "Earth", Mass: 1, Moons: []string{"Moon"}},
+	{Name: "Mars", Mass: 0.107, Moons: []string{"Phobos", "Deimos"}},
+}
+
+func TestRegisterSlice(t *testing.T) {
+	t.Parallel()
+
+	db, err := sqlite3.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = gotable.RegisterSlice(db, "planets", planets)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stmt, _, err := db.Prepare(`
+		SELECT name, json_array_length(moons)
+		FROM planets
+		WHERE mass < 1 ORDER BY name`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+
+	var got []string
+	for stmt.Step() {
+		got = append(got, stmt.ColumnText(0))
+		if name, n := stmt.ColumnText(0), stmt.ColumnInt(1); name == "Mars" && n != 2 {
+			t.Errorf("got %d moons", n)
+		}
+	}
+	if err := stmt.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != "Mars" || got[1] != "Mercury" {
+		t.Errorf("got %q", got)
+	}
+
+	err = db.Exec(`SELECT secret FROM planets`)
+	if err == nil {
+		t.Error("want error")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	t.Parallel()
+
+	db, err := sqlite3.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = gotable.Register(db, "series", func(arg ...sqlite3.Value) iter.Seq[int64] {
+		stop := int64(10)
+		step := int64(1)
+		if len(arg) > 0 {
+			stop = arg[0].Int64()
+		}
+		if len(arg) > 1 {
+			step = arg[1].Int64()
+		}
+		return func(yield func(int64) bool) {
+			for i := int64(0); i < stop; i += step {
+				if !yield(i) {
+					return
+				}
+			}
+		}
+	}, "stop", "step")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		sql  string
+		want int64
+	}{
+		{`SELECT sum(value) FROM series`, 45},
+		{`SELECT sum(value) FROM series(5)`, 10},
+		{`SELECT sum(value) FROM series(10, 3)`, 18},
+		{`SELECT sum(value) FROM series WHERE stop = 4`, 6},
+		{`SELECT sum(value) FROM series(100) WHERE value < 10`, 45},
+		{`SELECT count(*) FROM series(100) LIMIT 1`, 100},
+		{`SELECT stop FROM series(7)`, 7},
+		{`SELECT sum(step) FROM series(10, 3)`, 12},
+		{`SELECT stop FROM series WHERE stop = 4`, 4},
+		{`SELECT sum(value) FROM series WHERE stop = 4 AND stop = 4`, 6},
+		{`SELECT count(*) FROM series WHERE stop = 4 AND stop = 5`, 0},
+	}
+	for _, tt := range tests {
+		stmt, _, err := db.Prepare(tt.sql)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !stmt.Step() {
+			t.Fatal(stmt.Err())
+		}
+		if got := stmt.ColumnInt64(0); got != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.sql, got, tt.want)
+		}
+		stmt.Close()
+	}
+
+	err = db.Exec(`SELECT * FROM series WHERE step = 2`)
+	if err == nil {
+		t.Error("want error")
+	}
+}
@@ -2,9 +2,11 @@
 package serdes
 
 import (
+	"errors"
 	"io"
 
 	"github.com/ncruces/go-sqlite3"
+	"github.com/ncruces/go-sqlite3/internal/util"
 	"github.com/ncruces/go-sqlite3/vfs"
 )
 
@@ -14,15 +16,23 @@ func init() {
 	vfs.Register(vfsName, sliceVFS{})
 }
 
-var fileToOpen = make(chan *sliceFile, 1)
+var fileToOpen = make(chan vfs.File, 1)
 
 // Serialize backs up a database into a byte slice.
 //
+// Serialize uses [sqlite3.Conn.Serialize],
+// or an online backup if the SQLite binary doesn't export it.
+//
 // https://sqlite.org/c3ref/serialize.html
 func Serialize(db *sqlite3.Conn, schema string) ([]byte, error) {
+	data, err := db.Serialize(schema, 0)
+	if !errors.Is(err, util.NoExportErr) {
+		return data, err
+	}
+
 	var file sliceFile
 	fileToOpen <- &file
-	err := db.Backup(schema, "file:serdes.db?vfs="+vfsName)
+	err = db.Backup(schema, "file:serdes.db?vfs="+vfsName)
 	return file.data, err
 }
 
@@ -35,6 +45,7 @@ func Serialize(db *sqlite3.Conn, schema string) ([]byte, error) {
 // This differs from the similarly named SQLite API
 // in that it DOES NOT disconnect from schema
 // to reopen as an in-memory database.
+// For that, use [sqlite3.Conn.Deserialize].
 //
 // https://sqlite.org/c3ref/deserialize.html
 //
@@ -59,6 +59,46 @@ func TestDeserialize(t *testing.T) {
 	}
 }
 
+func TestSerialize(t *testing.T) {
+	t.Parallel()
+
+	db, err := sqlite3.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = db.Exec(`
+		CREATE TABLE test (col);
+		INSERT INTO test SELECT randomblob(1000) FROM generate_series(1, 1000);
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := serdes.Serialize(db, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = serdes.Deserialize(db, "temp", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stmt, _, err := db.Prepare(`SELECT count(*) FROM temp.test`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	if !stmt.Step() {
+		t.Fatal(stmt.Err())
+	}
+	if got := stmt.ColumnInt(0); got != 1000 {
+		t.Errorf("got %d rows, want 1000", got)
+	}
+}
+
 func httpGet() ([]byte, error) {
 	res, err := http.Get("https://raw.githubusercontent.com/jpwhite3/northwind-SQLite3/refs/heads/main/dist/northwind.db")
 	if err != nil {
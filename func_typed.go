@@ -0,0 +1,332 @@
+package sqlite3
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/ncruces/go-sqlite3/internal/util"
+)
+
+// Func0 defines a new scalar SQL function with no arguments.
+// See [Func2] for how results are encoded.
+//
+// https://sqlite.org/c3ref/create_function.html
+func Func0[R any](db *Conn, name string, flag FunctionFlag, fn func() (R, error)) error {
+	return db.CreateFunction(name, 0, flag, func(ctx Context, arg ...Value) {
+		res, err := fn()
+		typedResult(ctx, res, err)
+	})
+}
+
+// Func1 defines a new scalar SQL function with one argument.
+// See [Func2] for how arguments are decoded and results encoded.
+//
+// https://sqlite.org/c3ref/create_function.html
+func Func1[A, R any](db *Conn, name string, flag FunctionFlag, fn func(A) (R, error)) error {
+	decA := argDecoder[A]()
+	return db.CreateFunction(name, 1, flag, func(ctx Context, arg ...Value) {
+		a, null, err := decA(arg[0])
+		if null || err != nil {
+			typedResult(ctx, nil, err)
+			return
+		}
+		res, err := fn(a)
+		typedResult(ctx, res, err)
+	})
+}
+
+// Func2 defines a new scalar SQL function with two arguments.
+//
+// Arguments are decoded into integer, float, bool, string, []byte,
+// [time.Time] (using [TimeFormatAuto]), [Value] and any types;
+// other types are decoded from JSON.
+// If a NULL is passed to an argument that is not a pointer, interface or [Value],
+// fn is not called, and the result is NULL.
+// Pointers decode NULL as nil.
+//
+// Results are encoded similarly: nil pointers as NULL,
+// [time.Time] with [TimeFormatDefault], [ZeroBlob] as a zero-filled BLOB,
+// and types other than integer, float, bool, string, []byte and [Value] as JSON.
+// A non-nil error is returned as the error of the function.
+//
+// Flags are passed to [Conn.CreateFunction] unchanged.
+// Typed functions have no access to the database connection,
+// so [INNOCUOUS] is usually appropriate;
+// add [DETERMINISTIC] if fn always returns the same result for the same arguments.
+//
+// https://sqlite.org/c3ref/create_function.html
+func Func2[A, B, R any](db *Conn, name string, flag FunctionFlag, fn func(A, B) (R, error)) error {
+	decA := argDecoder[A]()
+	decB := argDecoder[B]()
+	return db.CreateFunction(name, 2, flag, func(ctx Context, arg ...Value) {
+		a, null, err := decA(arg[0])
+		if null || err != nil {
+			typedResult(ctx, nil, err)
+			return
+		}
+		b, null, err := decB(arg[1])
+		if null || err != nil {
+			typedResult(ctx, nil, err)
+			return
+		}
+		res, err := fn(a, b)
+		typedResult(ctx, res, err)
+	})
+}
+
+// Func3 defines a new scalar SQL function with three arguments.
+// See [Func2] for how arguments are decoded and results encoded.
+//
+// https://sqlite.org/c3ref/create_function.html
+func Func3[A, B, C, R any](db *Conn, name string, flag FunctionFlag, fn func(A, B, C) (R, error)) error {
+	decA := argDecoder[A]()
+	decB := argDecoder[B]()
+	decC := argDecoder[C]()
+	return db.CreateFunction(name, 3, flag, func(ctx Context, arg ...Value) {
+		a, null, err := decA(arg[0])
+		if null || err != nil {
+			typedResult(ctx, nil, err)
+			return
+		}
+		b, null, err := decB(arg[1])
+		if null || err != nil {
+			typedResult(ctx, nil, err)
+			return
+		}
+		c, null, err := decC(arg[2])
+		if null || err != nil {
+			typedResult(ctx, nil, err)
+			return
+		}
+		res, err := fn(a, b, c)
+		typedResult(ctx, res, err)
+	})
+}
+
+// Aggregate builds a typed aggregate SQL function
+// with state S, a single argument A, and result R.
+//
+// Arguments and results are decoded and encoded as described for [Func2].
+// Rows where a NULL is passed to an argument that is not
+// a pointer, interface or [Value] are skipped.
+type Aggregate[S, A, R any] struct {
+	// Step adds a row to the state.
+	Step func(state *S, arg A) error
+	// Inverse, if set, removes the oldest row added to the state,
+	// and makes this an aggregate window function.
+	Inverse func(state *S, arg A) error
+	// Final returns the result for the state.
+	// For window functions, it may be called more than once.
+	Final func(state *S) (R, error)
+}
+
+// Register defines the aggregate SQL function with db.
+// Flags are passed to [Conn.CreateWindowFunction] unchanged.
+//
+// https://sqlite.org/c3ref/create_function.html
+func (a Aggregate[S, A, R]) Register(db *Conn, name string, flag FunctionFlag) error {
+	if a.Step == nil || a.Final == nil {
+		return MISUSE
+	}
+	dec := argDecoder[A]()
+	fn := func() AggregateFunction {
+		return &typedAggregate[S, A, R]{Aggregate: &a, dec: dec}
+	}
+	if a.Inverse != nil {
+		fn = func() AggregateFunction {
+			return &typedWindow[S, A, R]{typedAggregate[S, A, R]{Aggregate: &a, dec: dec}}
+		}
+	}
+	return db.CreateWindowFunction(name, 1, flag, fn)
+}
+
+type typedAggregate[S, A, R any] struct {
+	*Aggregate[S, A, R]
+	dec   func(Value) (A, bool, error)
+	state S
+	err   error
+}
+
+func (t *typedAggregate[S, A, R]) Step(ctx Context, arg ...Value) {
+	t.apply(t.Aggregate.Step, arg[0])
+}
+
+func (t *typedAggregate[S, A, R]) Value(ctx Context) {
+	if t.err != nil {
+		ctx.ResultError(t.err)
+		return
+	}
+	res, err := t.Final(&t.state)
+	typedResult(ctx, res, err)
+}
+
+func (t *typedAggregate[S, A, R]) apply(fn func(*S, A) error, arg Value) {
+	if t.err != nil {
+		return
+	}
+	a, null, err := t.dec(arg)
+	if err == nil && !null {
+		err = fn(&t.state, a)
+	}
+	t.err = err
+}
+
+type typedWindow[S, A, R any] struct {
+	typedAggregate[S, A, R]
+}
+
+func (t *typedWindow[S, A, R]) Inverse(ctx Context, arg ...Value) {
+	t.apply(t.Aggregate.Inverse, arg[0])
+}
+
+var (
+	valueType    = reflect.TypeFor[Value]()
+	zeroBlobType = reflect.TypeFor[ZeroBlob]()
+)
+
+// argDecoder returns a function that decodes a Value into an A.
+// The function reports NULLs that A can't represent.
+func argDecoder[A any]() func(Value) (A, bool, error) {
+	typ := reflect.TypeFor[A]()
+	nullable := typ == valueType ||
+		typ.Kind() == reflect.Pointer ||
+		typ.Kind() == reflect.Interface
+	return func(arg Value) (a A, null bool, err error) {
+		if arg.Type() == NULL && !nullable {
+			return a, true, nil
+		}
+		err = decodeValue(arg, reflect.ValueOf(&a).Elem())
+		return a, false, err
+	}
+}
+
+func decodeValue(arg Value, v reflect.Value) error {
+	switch v.Type() {
+	case valueType:
+		v.Set(reflect.ValueOf(arg))
+		return nil
+	case timeType:
+		t, err := TimeFormatAuto.Decode(arg.valueAny())
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	if v.Kind() == reflect.Pointer {
+		if arg.Type() == NULL {
+			v.SetZero()
+			return nil
+		}
+		v.Set(reflect.New(v.Type().Elem()))
+		return decodeValue(arg, v.Elem())
+	}
+
+	switch k := v.Kind(); {
+	case v.CanInt():
+		i := arg.Int64()
+		if v.OverflowInt(i) {
+			return fmt.Errorf("sqlite3: integer overflow:%.0w %d", MISMATCH, i)
+		}
+		v.SetInt(i)
+	case v.CanUint():
+		i := arg.Int64()
+		if i < 0 || v.OverflowUint(uint64(i)) {
+			return fmt.Errorf("sqlite3: integer overflow:%.0w %d", MISMATCH, i)
+		}
+		v.SetUint(uint64(i))
+	case v.CanFloat():
+		v.SetFloat(arg.Float())
+	case k == reflect.Bool:
+		v.SetBool(arg.Bool())
+	case k == reflect.String:
+		v.SetString(arg.Text())
+	case k == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		v.SetBytes(arg.Blob(nil))
+	case k == reflect.Interface && v.NumMethod() == 0:
+		if a := arg.valueAny(); a != nil {
+			v.Set(reflect.ValueOf(a))
+		}
+	default:
+		return arg.JSON(v.Addr().Interface())
+	}
+	return nil
+}
+
+// valueAny returns the value as:
+// int64, float64, string, []byte (a copy), or nil.
+func (v Value) valueAny() any {
+	switch v.Type() {
+	case INTEGER:
+		return v.Int64()
+	case FLOAT:
+		return v.Float()
+	case TEXT:
+		return v.Text()
+	case BLOB:
+		return v.Blob(nil)
+	default:
+		return nil
+	}
+}
+
+func typedResult(ctx Context, res any, err error) {
+	if err != nil {
+		ctx.ResultError(err)
+		return
+	}
+	if err := encodeResult(ctx, reflect.ValueOf(res)); err != nil {
+		ctx.ResultError(err)
+	}
+}
+
+func encodeResult(ctx Context, v reflect.Value) error {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			break
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() || v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		ctx.ResultNull()
+		return nil
+	}
+
+	switch v.Type() {
+	case valueType:
+		ctx.ResultValue(v.Interface().(Value))
+		return nil
+	case timeType:
+		ctx.ResultTime(v.Interface().(time.Time), TimeFormatDefault)
+		return nil
+	case zeroBlobType:
+		ctx.ResultZeroBlob(v.Int())
+		return nil
+	}
+
+	switch k := v.Kind(); {
+	case v.CanInt():
+		ctx.ResultInt64(v.Int())
+	case v.CanUint():
+		i := int64(v.Uint())
+		if i < 0 {
+			return fmt.Errorf("sqlite3: integer overflow:%.0w %d", MISMATCH, v.Uint())
+		}
+		ctx.ResultInt64(i)
+	case v.CanFloat():
+		ctx.ResultFloat(v.Float())
+	case k == reflect.Bool:
+		ctx.ResultBool(v.Bool())
+	case k == reflect.String:
+		ctx.ResultText(v.String())
+	case k == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		ctx.ResultBlob(v.Bytes())
+	case k == reflect.Func || k == reflect.Chan || k == reflect.UnsafePointer:
+		return fmt.Errorf("sqlite3: unsupported result:%.0w %v", MISMATCH, util.ReflectType(v))
+	default:
+		ctx.ResultJSON(v.Interface())
+	}
+	return nil
+}
@@ -23,6 +23,9 @@ const (
 	IsolationErr = ErrorString("sqlite3: unsupported isolation level")
 	ValueErr     = ErrorString("sqlite3: unsupported value")
 	NoVFSErr     = ErrorString("sqlite3: no such vfs: ")
+	NoBlobErr    = ErrorString("sqlite3: no row with a zero-filled BLOB to write reader into")
+	NoExportErr  = ErrorString("sqlite3: SQLite binary does not export this API")
+	SeekErr      = ErrorString("sqlite3: read out of order")
 )
 
 func AssertErr() ErrorString {
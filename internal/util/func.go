@@ -90,6 +90,20 @@ func ExportFuncVIIIIJ[T0, T1, T2, T3 i32, T4 i64](mod wazero.HostModuleBuilder,
 		Export(name)
 }
 
+type funcVIIIIIJJ[T0, T1, T2, T3, T4 i32, T5, T6 i64] func(context.Context, api.Module, T0, T1, T2, T3, T4, T5, T6)
+
+func (fn funcVIIIIIJJ[T0, T1, T2, T3, T4, T5, T6]) Call(ctx context.Context, mod api.Module, stack []uint64) {
+	_ = stack[6] // prevent bounds check on every slice access
+	fn(ctx, mod, T0(stack[0]), T1(stack[1]), T2(stack[2]), T3(stack[3]), T4(stack[4]), T5(stack[5]), T6(stack[6]))
+}
+
+func ExportFuncVIIIIIJJ[T0, T1, T2, T3, T4 i32, T5, T6 i64](mod wazero.HostModuleBuilder, name string, fn func(context.Context, api.Module, T0, T1, T2, T3, T4, T5, T6)) {
+	mod.NewFunctionBuilder().
+		WithGoModuleFunction(funcVIIIIIJJ[T0, T1, T2, T3, T4, T5, T6](fn),
+			[]api.ValueType{api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI64, api.ValueTypeI64}, nil).
+		Export(name)
+}
+
 type funcII[TR, T0 i32] func(context.Context, api.Module, T0) TR
 
 func (fn funcII[TR, T0]) Call(ctx context.Context, mod api.Module, stack []uint64) {
@@ -1,6 +1,10 @@
 package util
 
-import "reflect"
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
 
 func ReflectType(v reflect.Value) reflect.Type {
 	if v.Kind() != reflect.Invalid {
@@ -8,3 +12,49 @@ func ReflectType(v reflect.Value) reflect.Type {
 	}
 	return nil
 }
+
+// StructField is an exported struct field,
+// named by its "sqlite" tag, or by the field's name.
+type StructField struct {
+	Name  string
+	Index []int
+	JSON  bool   // the "json" tag option
+	Time  string // the "time=" tag option
+}
+
+var structFieldsCache sync.Map // map[reflect.Type][]StructField
+
+// StructFields returns the fields of a struct type.
+// Fields tagged "-", unexported fields,
+// and untagged embedded structs (but not their fields) are skipped.
+func StructFields(typ reflect.Type) []StructField {
+	if fields, ok := structFieldsCache.Load(typ); ok {
+		return fields.([]StructField)
+	}
+
+	var fields []StructField
+	for _, f := range reflect.VisibleFields(typ) {
+		tag, tagged := f.Tag.Lookup("sqlite")
+		if tag == "-" || !f.IsExported() || f.Anonymous && !tagged {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		field := StructField{Name: name, Index: f.Index}
+		for _, opt := range strings.Split(opts, ",") {
+			switch {
+			case opt == "json":
+				field.JSON = true
+			case strings.HasPrefix(opt, "time="):
+				field.Time = opt[len("time="):]
+			}
+		}
+		fields = append(fields, field)
+	}
+
+	structFieldsCache.Store(typ, fields)
+	return fields
+}
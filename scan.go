@@ -0,0 +1,228 @@
+package sqlite3
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/ncruces/go-sqlite3/internal/util"
+)
+
+// ScanStruct scans the current row of stmt into a new value of struct type T.
+//
+// Result columns are matched, case-insensitively, to exported struct fields
+// by the name in the field's "sqlite" tag, or by the field's name.
+// Columns with no matching field are ignored,
+// and fields tagged "-" are never matched.
+//
+// Fields that implement [sql.Scanner] use it.
+// Fields with the "json" tag option are decoded with [Stmt.ColumnJSON].
+// [time.Time] fields are decoded with the [TimeFormat] named
+// by the "time" tag option, or [TimeFormatAuto]:
+//
+//	type User struct {
+//		ID      int64     `sqlite:"id"`
+//		Name    string    `sqlite:"name"`
+//		Tags    []string  `sqlite:"tags,json"`
+//		Created time.Time `sqlite:"created,time=unixepoch"`
+//		Secret  string    `sqlite:"-"`
+//	}
+//
+// A NULL scanned into a pointer field sets it to nil;
+// into any other field, it sets the field to its zero value.
+func ScanStruct[T any](stmt *Stmt) (T, error) {
+	var val T
+	scan, err := newStructScanner(stmt, reflect.TypeFor[T]())
+	if err == nil {
+		err = scan.scan(stmt, reflect.ValueOf(&val).Elem())
+	}
+	return val, err
+}
+
+// QueryStructs runs an SQL statement with args bound to its parameters,
+// and scans every result row into a value of struct type T,
+// as described for [ScanStruct].
+// Only the first statement in sql is run.
+func QueryStructs[T any](c *Conn, sql string, args ...any) ([]T, error) {
+	var res []T
+	var scan structScanner
+	var err error
+	c.queryIter(sql, args, func(stmt *Stmt, e error) bool {
+		if err = e; err != nil {
+			return false
+		}
+		if scan.fields == nil {
+			scan, err = newStructScanner(stmt, reflect.TypeFor[T]())
+			if err != nil {
+				return false
+			}
+		}
+		var val T
+		if err = scan.scan(stmt, reflect.ValueOf(&val).Elem()); err != nil {
+			return false
+		}
+		res = append(res, val)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func structFields(typ reflect.Type) ([]util.StructField, error) {
+	if typ.Kind() != reflect.Struct {
+		return nil, util.ValueErr
+	}
+	return util.StructFields(typ), nil
+}
+
+// structScanner maps each result column to a struct field.
+type structScanner struct {
+	fields []*util.StructField
+}
+
+func newStructScanner(stmt *Stmt, typ reflect.Type) (structScanner, error) {
+	fields, err := structFields(typ)
+	if err != nil {
+		return structScanner{}, err
+	}
+
+	scan := structScanner{make([]*util.StructField, stmt.ColumnCount())}
+	for col := range scan.fields {
+		name := stmt.ColumnName(col)
+		for i := range fields {
+			if strings.EqualFold(fields[i].Name, name) {
+				scan.fields[col] = &fields[i]
+				break
+			}
+		}
+	}
+	return scan, nil
+}
+
+func (s structScanner) scan(stmt *Stmt, val reflect.Value) error {
+	for col, f := range s.fields {
+		if f == nil {
+			continue
+		}
+		field, err := fieldByIndex(val, f.Index, true)
+		if err == nil {
+			err = stmt.scanField(col, f, field)
+		}
+		if err != nil {
+			return fmt.Errorf("sqlite3: scanning column %q: %w", stmt.ColumnName(col), err)
+		}
+	}
+	return nil
+}
+
+// fieldByIndex is like [reflect.Value.FieldByIndex],
+// but it can allocate nil embedded struct pointers.
+func fieldByIndex(v reflect.Value, index []int, alloc bool) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !alloc || !v.CanSet() {
+					return reflect.Value{}, util.ValueErr
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
+
+var (
+	scannerType = reflect.TypeFor[sql.Scanner]()
+	timeType    = reflect.TypeFor[time.Time]()
+)
+
+func (s *Stmt) scanField(col int, f *util.StructField, v reflect.Value) error {
+	if f.JSON {
+		return s.ColumnJSON(col, v.Addr().Interface())
+	}
+
+	if reflect.PointerTo(v.Type()).Implements(scannerType) {
+		return v.Addr().Interface().(sql.Scanner).Scan(s.columnAny(col))
+	}
+
+	if s.ColumnType(col) == NULL {
+		v.SetZero()
+		return nil
+	}
+
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return s.scanField(col, f, v.Elem())
+	}
+
+	if v.Type() == timeType {
+		format := TimeFormat(f.Time)
+		if format == TimeFormatDefault {
+			format = TimeFormatAuto
+		}
+		t, err := format.Decode(s.columnAny(col))
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch k := v.Kind(); {
+	case v.CanInt():
+		i := s.ColumnInt64(col)
+		if v.OverflowInt(i) {
+			return util.ValueErr
+		}
+		v.SetInt(i)
+	case v.CanUint():
+		i := s.ColumnInt64(col)
+		if i < 0 || v.OverflowUint(uint64(i)) {
+			return util.ValueErr
+		}
+		v.SetUint(uint64(i))
+	case v.CanFloat():
+		r := s.ColumnFloat(col)
+		if v.OverflowFloat(r) && !math.IsInf(r, 0) {
+			return util.ValueErr
+		}
+		v.SetFloat(r)
+	case k == reflect.Bool:
+		v.SetBool(s.ColumnBool(col))
+	case k == reflect.String:
+		v.SetString(s.ColumnText(col))
+	case k == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		v.SetBytes(s.ColumnBlob(col, v.Bytes()[:0]))
+	case k == reflect.Interface && v.NumMethod() == 0:
+		v.Set(reflect.ValueOf(s.columnAny(col)))
+	default:
+		return util.ValueErr
+	}
+	return nil
+}
+
+// columnAny returns the value of the result column as:
+// int64, float64, string, []byte (a copy), or nil.
+func (s *Stmt) columnAny(col int) any {
+	switch s.ColumnType(col) {
+	case INTEGER:
+		return s.ColumnInt64(col)
+	case FLOAT:
+		return s.ColumnFloat(col)
+	case TEXT:
+		return s.ColumnText(col)
+	case BLOB:
+		return s.ColumnBlob(col, nil)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,80 @@
+package sqlite3
+
+import "github.com/ncruces/go-sqlite3/internal/util"
+
+// Serialize returns the serialization of the schema database:
+// the same sequence of bytes that would be written
+// to disk if that database were backed up to a file.
+//
+// With [SERIALIZE_NOCOPY], the returned slice references
+// the memory that holds a database opened with [Conn.Deserialize],
+// and is only valid until the next change to the database,
+// or the next call to any method of the connection.
+// If the schema database was not opened with [Conn.Deserialize],
+// [SERIALIZE_NOCOPY] returns nil.
+//
+// https://sqlite.org/c3ref/serialize.html
+func (c *Conn) Serialize(schema string, flags SerializeFlag) ([]byte, error) {
+	if err := c.needfn("sqlite3_serialize"); err != nil {
+		return nil, err
+	}
+	if schema == "" {
+		schema = "main"
+	}
+
+	defer c.arena.mark()()
+	sizePtr := c.arena.new(8)
+	schemaPtr := c.arena.string(schema)
+
+	c.checkInterrupt(c.handle)
+	ptr := ptr_t(c.call("sqlite3_serialize", stk_t(c.handle),
+		stk_t(schemaPtr), stk_t(sizePtr), stk_t(flags)))
+	if ptr == 0 {
+		if flags&SERIALIZE_NOCOPY != 0 {
+			return nil, nil
+		}
+		rc := res_t(c.call("sqlite3_errcode", stk_t(c.handle)))
+		if err := c.error(rc); err != nil {
+			return nil, err
+		}
+		return nil, ERROR
+	}
+
+	size := util.Read64[int64](c.mod, sizePtr)
+	if flags&SERIALIZE_NOCOPY != 0 {
+		return util.View(c.mod, ptr, size), nil
+	}
+	defer c.free(ptr)
+	return append([]byte(nil), util.View(c.mod, ptr, size)...), nil
+}
+
+// Deserialize disconnects from the schema database,
+// and reopens it as an in-memory database with a copy of data.
+// Contents previously stored in schema are NOT changed,
+// but are no longer accessible through this connection.
+//
+// Unless [DESERIALIZE_RESIZEABLE] is used, the database can not grow
+// beyond its initial size. With [DESERIALIZE_RESIZEABLE],
+// use [FCNTL_SIZE_LIMIT] to limit how large the database can grow:
+//
+//	db.FileControl(schema, sqlite3.FCNTL_SIZE_LIMIT, maxSize)
+//
+// https://sqlite.org/c3ref/deserialize.html
+func (c *Conn) Deserialize(schema string, data []byte, flags DeserializeFlag) error {
+	if err := c.needfn("sqlite3_deserialize_go"); err != nil {
+		return err
+	}
+	if schema == "" {
+		schema = "main"
+	}
+
+	defer c.arena.mark()()
+	schemaPtr := c.arena.string(schema)
+
+	// SQLite takes ownership of the buffer, freeing it when closed.
+	dataPtr := c.newBytes(data)
+
+	rc := res_t(c.call("sqlite3_deserialize_go", stk_t(c.handle),
+		stk_t(schemaPtr), stk_t(dataPtr), stk_t(len(data)), stk_t(flags)))
+	return c.error(rc)
+}
@@ -0,0 +1,462 @@
+package sqlite3
+
+import (
+	"context"
+
+	"github.com/tetratelabs/wazero/api"
+
+	"github.com/ncruces/go-sqlite3/internal/util"
+)
+
+// Session is a session object that records changes to a database.
+//
+// https://sqlite.org/sessionintro.html
+type Session struct {
+	c      *Conn
+	handle ptr_t
+}
+
+// CreateSession creates a new session object
+// that records changes to the schema database.
+//
+// https://sqlite.org/session/sqlite3session_create.html
+func (c *Conn) CreateSession(schema string) (*Session, error) {
+	if err := c.needfn("sqlite3session_create"); err != nil {
+		return nil, err
+	}
+	if schema == "" {
+		schema = "main"
+	}
+
+	defer c.arena.mark()()
+	sessionPtr := c.arena.new(ptrlen)
+	schemaPtr := c.arena.string(schema)
+
+	rc := res_t(c.call("sqlite3session_create", stk_t(c.handle),
+		stk_t(schemaPtr), stk_t(sessionPtr)))
+	if err := c.error(rc); err != nil {
+		return nil, err
+	}
+
+	session := Session{c: c}
+	session.handle = util.Read32[ptr_t](c.mod, sessionPtr)
+	return &session, nil
+}
+
+// Close deletes the session object.
+// A session must be closed before the connection it was created on.
+//
+// It is safe to close a nil, zero or closed Session.
+//
+// https://sqlite.org/session/sqlite3session_delete.html
+func (s *Session) Close() error {
+	if s == nil || s.handle == 0 {
+		return nil
+	}
+
+	s.c.call("sqlite3session_delete", stk_t(s.handle))
+	s.handle = 0
+	return nil
+}
+
+// Attach attaches a table to the session object.
+// If table is empty, changes are recorded for all tables.
+//
+// https://sqlite.org/session/sqlite3session_attach.html
+func (s *Session) Attach(table string) error {
+	var tablePtr ptr_t
+	if table != "" {
+		defer s.c.arena.mark()()
+		tablePtr = s.c.arena.string(table)
+	}
+	rc := res_t(s.c.call("sqlite3session_attach", stk_t(s.handle), stk_t(tablePtr)))
+	return s.c.error(rc)
+}
+
+// Enable enables or disables the recording of changes.
+// Called with no arg queries the current state,
+// called with one arg sets and returns the new state.
+//
+// https://sqlite.org/session/sqlite3session_enable.html
+func (s *Session) Enable(arg ...bool) bool {
+	b := int32(s.c.call("sqlite3session_enable", stk_t(s.handle), stk_t(boolFlag(arg))))
+	return b != 0
+}
+
+// Indirect sets or clears the indirect change flag.
+// Called with no arg queries the current state,
+// called with one arg sets and returns the new state.
+//
+// https://sqlite.org/session/sqlite3session_indirect.html
+func (s *Session) Indirect(arg ...bool) bool {
+	b := int32(s.c.call("sqlite3session_indirect", stk_t(s.handle), stk_t(boolFlag(arg))))
+	return b != 0
+}
+
+// IsEmpty returns true if no changes have been recorded by the session.
+//
+// https://sqlite.org/session/sqlite3session_isempty.html
+func (s *Session) IsEmpty() bool {
+	b := int32(s.c.call("sqlite3session_isempty", stk_t(s.handle)))
+	return b != 0
+}
+
+// Diff records the changes needed to make table in the fromSchema database
+// match the same table in the database attached to the session.
+//
+// https://sqlite.org/session/sqlite3session_diff.html
+func (s *Session) Diff(fromSchema, table string) error {
+	defer s.c.arena.mark()()
+	errPtr := s.c.arena.new(ptrlen)
+	fromPtr := s.c.arena.string(fromSchema)
+	tablePtr := s.c.arena.string(table)
+
+	rc := res_t(s.c.call("sqlite3session_diff", stk_t(s.handle),
+		stk_t(fromPtr), stk_t(tablePtr), stk_t(errPtr)))
+
+	var msg string
+	if ptr := util.Read32[ptr_t](s.c.mod, errPtr); ptr != 0 {
+		msg = util.ReadString(s.c.mod, ptr, _MAX_LENGTH)
+		s.c.free(ptr)
+	}
+
+	err := s.c.error(rc)
+	if err, ok := err.(*Error); ok && msg != "" {
+		err.msg = msg
+	}
+	return err
+}
+
+// Changeset returns a changeset with the changes recorded by the session.
+//
+// https://sqlite.org/session/sqlite3session_changeset.html
+func (s *Session) Changeset() ([]byte, error) {
+	return s.changeset("sqlite3session_changeset")
+}
+
+// Patchset returns a patchset with the changes recorded by the session.
+//
+// https://sqlite.org/session/sqlite3session_patchset.html
+func (s *Session) Patchset() ([]byte, error) {
+	return s.changeset("sqlite3session_patchset")
+}
+
+func (s *Session) changeset(name string) ([]byte, error) {
+	defer s.c.arena.mark()()
+	sizePtr := s.c.arena.new(intlen)
+	dataPtr := s.c.arena.new(ptrlen)
+
+	rc := res_t(s.c.call(name, stk_t(s.handle), stk_t(sizePtr), stk_t(dataPtr)))
+	if err := s.c.error(rc); err != nil {
+		return nil, err
+	}
+	return s.c.changesetBytes(sizePtr, dataPtr), nil
+}
+
+// InvertChangeset returns the inverse of a changeset.
+//
+// https://sqlite.org/session/sqlite3changeset_invert.html
+func (c *Conn) InvertChangeset(changeset []byte) ([]byte, error) {
+	if err := c.needfn("sqlite3changeset_invert"); err != nil {
+		return nil, err
+	}
+	defer c.arena.mark()()
+	sizePtr := c.arena.new(intlen)
+	dataPtr := c.arena.new(ptrlen)
+	inPtr := c.arena.bytes(changeset)
+
+	rc := res_t(c.call("sqlite3changeset_invert",
+		stk_t(len(changeset)), stk_t(inPtr),
+		stk_t(sizePtr), stk_t(dataPtr)))
+	if err := c.error(rc); err != nil {
+		return nil, err
+	}
+	return c.changesetBytes(sizePtr, dataPtr), nil
+}
+
+// ConcatChangesets returns a changeset (or patchset)
+// that has the same effect as applying a and then b.
+//
+// https://sqlite.org/session/sqlite3changeset_concat.html
+func (c *Conn) ConcatChangesets(a, b []byte) ([]byte, error) {
+	if err := c.needfn("sqlite3changeset_concat"); err != nil {
+		return nil, err
+	}
+	defer c.arena.mark()()
+	sizePtr := c.arena.new(intlen)
+	dataPtr := c.arena.new(ptrlen)
+	aPtr := c.arena.bytes(a)
+	bPtr := c.arena.bytes(b)
+
+	rc := res_t(c.call("sqlite3changeset_concat",
+		stk_t(len(a)), stk_t(aPtr),
+		stk_t(len(b)), stk_t(bPtr),
+		stk_t(sizePtr), stk_t(dataPtr)))
+	if err := c.error(rc); err != nil {
+		return nil, err
+	}
+	return c.changesetBytes(sizePtr, dataPtr), nil
+}
+
+func (c *Conn) changesetBytes(sizePtr, dataPtr ptr_t) []byte {
+	size := util.Read32[int32](c.mod, sizePtr)
+	ptr := util.Read32[ptr_t](c.mod, dataPtr)
+	if ptr == 0 {
+		return []byte{}
+	}
+	buf := make([]byte, size)
+	copy(buf, util.View(c.mod, ptr, int64(size)))
+	c.free(ptr)
+	return buf
+}
+
+// ApplyChangeset applies a changeset (or patchset) to the database.
+//
+// If filter is not nil, changes to tables for which filter returns false are skipped.
+// The conflict handler is invoked for each conflicting change;
+// if conflict is nil, conflicting changes abort the operation.
+//
+// https://sqlite.org/session/sqlite3changeset_apply.html
+func (c *Conn) ApplyChangeset(changeset []byte,
+	filter func(table string) bool,
+	conflict func(ConflictType, *ChangesetIter) ConflictAction) error {
+	if err := c.needfn("sqlite3changeset_apply_go"); err != nil {
+		return err
+	}
+	defer c.arena.mark()()
+	dataPtr := c.arena.bytes(changeset)
+
+	var enable int32
+	if filter != nil {
+		enable = 1
+	}
+	app := util.AddHandle(c.ctx, &changesetHandlers{filter, conflict})
+
+	c.checkInterrupt(c.handle)
+	rc := res_t(c.call("sqlite3changeset_apply_go", stk_t(c.handle),
+		stk_t(len(changeset)), stk_t(dataPtr),
+		stk_t(app), stk_t(enable)))
+	return c.error(rc)
+}
+
+type changesetHandlers struct {
+	filter   func(table string) bool
+	conflict func(ConflictType, *ChangesetIter) ConflictAction
+}
+
+func changesetFilterCallback(ctx context.Context, mod api.Module, pApp, zTab ptr_t) (include int32) {
+	h := util.GetHandle(ctx, pApp).(*changesetHandlers)
+	if h.filter(util.ReadString(mod, zTab, _MAX_NAME)) {
+		include = 1
+	}
+	return include
+}
+
+func changesetConflictCallback(ctx context.Context, mod api.Module, pApp ptr_t, eConflict ConflictType, pIter ptr_t) ConflictAction {
+	h := util.GetHandle(ctx, pApp).(*changesetHandlers)
+	if h.conflict == nil {
+		return CHANGESET_ABORT
+	}
+	c := ctx.Value(connKey{}).(*Conn)
+	return h.conflict(eConflict, &ChangesetIter{c: c, handle: pIter})
+}
+
+// ChangesetIter is an iterator over the changes in a changeset.
+//
+// An iterator passed to a conflict handler is owned by SQLite,
+// and must not be advanced or closed.
+//
+// https://sqlite.org/session/changeset_iter.html
+type ChangesetIter struct {
+	c      *Conn
+	err    error
+	handle ptr_t
+	bufptr ptr_t // zero if owned by SQLite
+}
+
+// OpenChangeset creates an iterator over the changes in a changeset (or patchset).
+//
+// https://sqlite.org/session/sqlite3changeset_start.html
+func (c *Conn) OpenChangeset(changeset []byte) (*ChangesetIter, error) {
+	if err := c.needfn("sqlite3changeset_start"); err != nil {
+		return nil, err
+	}
+	defer c.arena.mark()()
+	iterPtr := c.arena.new(ptrlen)
+	// The changeset must outlive the iterator.
+	bufptr := c.new(max(1, int64(len(changeset))))
+	util.WriteBytes(c.mod, bufptr, changeset)
+
+	rc := res_t(c.call("sqlite3changeset_start", stk_t(iterPtr),
+		stk_t(len(changeset)), stk_t(bufptr)))
+	if err := c.error(rc); err != nil {
+		c.free(bufptr)
+		return nil, err
+	}
+
+	iter := ChangesetIter{c: c, bufptr: bufptr}
+	iter.handle = util.Read32[ptr_t](c.mod, iterPtr)
+	return &iter, nil
+}
+
+// Close finalizes the iterator.
+//
+// It is safe to close a nil, zero or closed ChangesetIter.
+//
+// https://sqlite.org/session/sqlite3changeset_finalize.html
+func (it *ChangesetIter) Close() error {
+	if it == nil || it.handle == 0 || it.bufptr == 0 {
+		return nil
+	}
+
+	rc := res_t(it.c.call("sqlite3changeset_finalize", stk_t(it.handle)))
+	it.c.free(it.bufptr)
+	it.handle = 0
+	it.bufptr = 0
+	return it.c.error(rc)
+}
+
+// Next advances the iterator to the next change.
+// If an error has occurred, Next returns false;
+// call [ChangesetIter.Err] to get the error.
+//
+// https://sqlite.org/session/sqlite3changeset_next.html
+func (it *ChangesetIter) Next() bool {
+	rc := res_t(it.c.call("sqlite3changeset_next", stk_t(it.handle)))
+	switch rc {
+	case _ROW:
+		it.err = nil
+		return true
+	case _DONE:
+		it.err = nil
+	default:
+		it.err = it.c.error(rc)
+	}
+	return false
+}
+
+// Err gets the last error occurred during [ChangesetIter.Next].
+func (it *ChangesetIter) Err() error {
+	return it.err
+}
+
+// Operation returns the table, number of columns,
+// and the type of the current change:
+// one of [AUTH_INSERT], [AUTH_UPDATE] or [AUTH_DELETE].
+//
+// https://sqlite.org/session/sqlite3changeset_op.html
+func (it *ChangesetIter) Operation() (table string, columns int, op AuthorizerActionCode, indirect bool, err error) {
+	defer it.c.arena.mark()()
+	tablePtr := it.c.arena.new(ptrlen)
+	columnsPtr := it.c.arena.new(intlen)
+	opPtr := it.c.arena.new(intlen)
+	indirectPtr := it.c.arena.new(intlen)
+
+	rc := res_t(it.c.call("sqlite3changeset_op", stk_t(it.handle),
+		stk_t(tablePtr), stk_t(columnsPtr), stk_t(opPtr), stk_t(indirectPtr)))
+	if err = it.c.error(rc); err == nil {
+		table = util.ReadString(it.c.mod, util.Read32[ptr_t](it.c.mod, tablePtr), _MAX_NAME)
+		columns = int(util.Read32[int32](it.c.mod, columnsPtr))
+		op = util.Read32[AuthorizerActionCode](it.c.mod, opPtr)
+		indirect = util.ReadBool(it.c.mod, indirectPtr)
+	}
+	return
+}
+
+// PrimaryKey returns which columns of the table
+// of the current change make up its primary key.
+//
+// https://sqlite.org/session/sqlite3changeset_pk.html
+func (it *ChangesetIter) PrimaryKey() ([]bool, error) {
+	defer it.c.arena.mark()()
+	pkPtr := it.c.arena.new(ptrlen)
+	columnsPtr := it.c.arena.new(intlen)
+
+	rc := res_t(it.c.call("sqlite3changeset_pk", stk_t(it.handle),
+		stk_t(pkPtr), stk_t(columnsPtr)))
+	if err := it.c.error(rc); err != nil {
+		return nil, err
+	}
+
+	columns := util.Read32[int32](it.c.mod, columnsPtr)
+	flags := util.View(it.c.mod, util.Read32[ptr_t](it.c.mod, pkPtr), int64(columns))
+	pk := make([]bool, columns)
+	for i, f := range flags {
+		pk[i] = f != 0
+	}
+	return pk, nil
+}
+
+// Old returns the original value of a column of the current
+// [AUTH_UPDATE] or [AUTH_DELETE] change.
+// If the value is not available, Old returns the zero Value.
+// The leftmost column has the index 0.
+//
+// https://sqlite.org/session/sqlite3changeset_old.html
+func (it *ChangesetIter) Old(col int) (Value, error) {
+	return it.value("sqlite3changeset_old", col)
+}
+
+// New returns the new value of a column of the current
+// [AUTH_UPDATE] or [AUTH_INSERT] change.
+// If the value is not available, New returns the zero Value.
+// The leftmost column has the index 0.
+//
+// https://sqlite.org/session/sqlite3changeset_new.html
+func (it *ChangesetIter) New(col int) (Value, error) {
+	return it.value("sqlite3changeset_new", col)
+}
+
+// Conflict returns the conflicting value of a column
+// of the change passed to a [CHANGESET_DATA] or [CHANGESET_CONFLICT] handler.
+// The leftmost column has the index 0.
+//
+// https://sqlite.org/session/sqlite3changeset_conflict.html
+func (it *ChangesetIter) Conflict(col int) (Value, error) {
+	return it.value("sqlite3changeset_conflict", col)
+}
+
+func (it *ChangesetIter) value(name string, col int) (Value, error) {
+	defer it.c.arena.mark()()
+	valPtr := it.c.arena.new(ptrlen)
+
+	rc := res_t(it.c.call(name, stk_t(it.handle), stk_t(col), stk_t(valPtr)))
+	if err := it.c.error(rc); err != nil {
+		return Value{}, err
+	}
+
+	ptr := util.Read32[ptr_t](it.c.mod, valPtr)
+	if ptr == 0 {
+		return Value{}, nil
+	}
+	return Value{
+		c:      it.c,
+		handle: ptr,
+	}, nil
+}
+
+// ForeignKeyConflicts returns the number of foreign key violations
+// in the change passed to a [CHANGESET_FOREIGN_KEY] handler.
+//
+// https://sqlite.org/session/sqlite3changeset_fk_conflicts.html
+func (it *ChangesetIter) ForeignKeyConflicts() (int, error) {
+	defer it.c.arena.mark()()
+	countPtr := it.c.arena.new(intlen)
+
+	rc := res_t(it.c.call("sqlite3changeset_fk_conflicts",
+		stk_t(it.handle), stk_t(countPtr)))
+	if err := it.c.error(rc); err != nil {
+		return 0, err
+	}
+	return int(util.Read32[int32](it.c.mod, countPtr)), nil
+}
+
+func boolFlag(arg []bool) (flag int32) {
+	switch {
+	case len(arg) == 0:
+		flag = -1
+	case arg[0]:
+		flag = 1
+	}
+	return flag
+}
@@ -0,0 +1,111 @@
+package sqlite3
+
+import "github.com/ncruces/go-sqlite3/internal/util"
+
+// Snapshot is a point in the history of a WAL mode database.
+//
+// A Snapshot is not tied to the connection that created it:
+// it can be opened by any connection to the same database.
+//
+// https://sqlite.org/c3ref/snapshot.html
+type Snapshot struct {
+	hidden [48]byte
+}
+
+// GetSnapshot records a snapshot of the current state of the schema database.
+// The connection must be in a read transaction of a WAL mode database.
+//
+// https://sqlite.org/c3ref/snapshot_get.html
+func (c *Conn) GetSnapshot(schema string) (*Snapshot, error) {
+	if err := c.needfn("sqlite3_snapshot_get"); err != nil {
+		return nil, err
+	}
+	if schema == "" {
+		schema = "main"
+	}
+
+	defer c.arena.mark()()
+	snapPtr := c.arena.new(ptrlen)
+	schemaPtr := c.arena.string(schema)
+
+	rc := res_t(c.call("sqlite3_snapshot_get", stk_t(c.handle),
+		stk_t(schemaPtr), stk_t(snapPtr)))
+	if err := c.error(rc); err != nil {
+		return nil, err
+	}
+
+	var snap Snapshot
+	ptr := util.Read32[ptr_t](c.mod, snapPtr)
+	copy(snap.hidden[:], util.View(c.mod, ptr, int64(len(snap.hidden))))
+	c.call("sqlite3_snapshot_free", stk_t(ptr))
+	return &snap, nil
+}
+
+// OpenSnapshot starts a read transaction on the schema database
+// that sees the database as it was when the snapshot was recorded.
+// The connection must not be in autocommit mode,
+// and must not have a read transaction open on the schema database:
+//
+//	tx := db.Begin()
+//	err := db.OpenSnapshot("main", snap)
+//
+// https://sqlite.org/c3ref/snapshot_open.html
+func (c *Conn) OpenSnapshot(schema string, snap *Snapshot) error {
+	if err := c.needfn("sqlite3_snapshot_open"); err != nil {
+		return err
+	}
+	if schema == "" {
+		schema = "main"
+	}
+
+	defer c.arena.mark()()
+	snapPtr := c.arena.bytes(snap.hidden[:])
+	schemaPtr := c.arena.string(schema)
+
+	c.checkInterrupt(c.handle)
+	rc := res_t(c.call("sqlite3_snapshot_open", stk_t(c.handle),
+		stk_t(schemaPtr), stk_t(snapPtr)))
+	return c.error(rc)
+}
+
+// RecoverSnapshot recovers snapshots from the WAL file of the schema database,
+// making them available to [Conn.OpenSnapshot]
+// after the last connection to the database has been closed.
+//
+// https://sqlite.org/c3ref/snapshot_recover.html
+func (c *Conn) RecoverSnapshot(schema string) error {
+	if err := c.needfn("sqlite3_snapshot_recover"); err != nil {
+		return err
+	}
+	if schema == "" {
+		schema = "main"
+	}
+
+	defer c.arena.mark()()
+	schemaPtr := c.arena.string(schema)
+
+	rc := res_t(c.call("sqlite3_snapshot_recover", stk_t(c.handle), stk_t(schemaPtr)))
+	return c.error(rc)
+}
+
+// CompareSnapshots orders two snapshots of the same database.
+// It returns a negative value if s1 is older than s2,
+// zero if both represent the same state,
+// and a positive value if s1 is newer than s2.
+//
+// Comparing snapshots of different databases,
+// or across a WAL file reset, is undefined.
+//
+// https://sqlite.org/c3ref/snapshot_cmp.html
+func (c *Conn) CompareSnapshots(s1, s2 *Snapshot) (int, error) {
+	if err := c.needfn("sqlite3_snapshot_cmp"); err != nil {
+		return 0, err
+	}
+
+	defer c.arena.mark()()
+	ptr1 := c.arena.bytes(s1.hidden[:])
+	ptr2 := c.arena.bytes(s2.hidden[:])
+
+	res := int32(c.call("sqlite3_snapshot_cmp", stk_t(ptr1), stk_t(ptr2)))
+	return int(res), nil
+}
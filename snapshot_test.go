@@ -0,0 +1,52 @@
+package sqlite3
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/ncruces/go-sqlite3/internal/util"
+)
+
+func TestConn_CompareSnapshots(t *testing.T) {
+	t.Parallel()
+
+	db, err := Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// A snapshot is a copy of the WAL-index header:
+	// sqlite3_snapshot_cmp compares the first salt,
+	// then the index of the last valid frame in the WAL.
+	snapshot := func(salt, frame uint32) *Snapshot {
+		var s Snapshot
+		binary.LittleEndian.PutUint32(s.hidden[16:], frame)
+		binary.LittleEndian.PutUint32(s.hidden[32:], salt)
+		return &s
+	}
+
+	tests := []struct {
+		a, b *Snapshot
+		want int
+	}{
+		{snapshot(1, 10), snapshot(1, 10), 0},
+		{snapshot(1, 10), snapshot(1, 20), -1},
+		{snapshot(1, 20), snapshot(1, 10), +1},
+		{snapshot(1, 20), snapshot(2, 10), -1},
+		{snapshot(2, 10), snapshot(1, 20), +1},
+	}
+	for _, tt := range tests {
+		got, err := db.CompareSnapshots(tt.a, tt.b)
+		if errors.Is(err, util.NoExportErr) {
+			t.Skip(err)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("CompareSnapshots() = %d, want %d", got, tt.want)
+		}
+	}
+}
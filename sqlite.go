@@ -26,6 +26,13 @@ var (
 	Path   string // Path to load the binary from.
 
 	RuntimeConfig wazero.RuntimeConfig
+
+	// CacheDir is a directory where compiled code is cached,
+	// speeding up [Initialize] in subsequent runs.
+	// Cached code is keyed by a hash of the binary
+	// (and the version of wazero), so it's safe to share
+	// the directory between different builds of SQLite.
+	CacheDir string
 )
 
 // Initialize decodes and compiles the SQLite Wasm binary.
@@ -55,6 +62,14 @@ func compileSQLite() {
 		}
 		cfg = cfg.WithCoreFeatures(api.CoreFeaturesV2)
 	}
+	if CacheDir != "" {
+		cache, err := wazero.NewCompilationCacheWithDir(CacheDir)
+		if err != nil {
+			instance.err = err
+			return
+		}
+		cfg = cfg.WithCompilationCache(cache)
+	}
 
 	instance.runtime = wazero.NewRuntimeWithConfig(ctx, cfg)
 
@@ -138,6 +153,8 @@ func (sqlt *sqlite) error(rc res_t, handle ptr_t, sql ...string) error {
 		if len(sql) != 0 {
 			if i := int32(sqlt.call("sqlite3_error_offset", stk_t(handle))); i != -1 {
 				err.sql = sql[0][i:]
+				err.off = int(i)
+				err.pos = true
 			}
 		}
 	}
@@ -177,6 +194,18 @@ func (sqlt *sqlite) putfn(name string, fn api.Function) {
 	}
 }
 
+// needfn returns an error if the SQLite binary
+// does not export the named function,
+// as is the case with binaries built without some extensions.
+func (sqlt *sqlite) needfn(name string) error {
+	fn := sqlt.getfn(name)
+	if fn == nil {
+		return util.NoExportErr
+	}
+	sqlt.putfn(name, fn)
+	return nil
+}
+
 func (sqlt *sqlite) call(name string, params ...stk_t) stk_t {
 	copy(sqlt.stack[:], params)
 	fn := sqlt.getfn(name)
@@ -309,7 +338,10 @@ func exportCallbacks(env wazero.HostModuleBuilder) wazero.HostModuleBuilder {
 	util.ExportFuncII(env, "go_commit_hook", commitCallback)
 	util.ExportFuncVI(env, "go_rollback_hook", rollbackCallback)
 	util.ExportFuncVIIIIJ(env, "go_update_hook", updateCallback)
+	util.ExportFuncVIIIIIJJ(env, "go_preupdate_hook", preUpdateCallback)
 	util.ExportFuncIIIII(env, "go_wal_hook", walCallback)
+	util.ExportFuncIII(env, "go_changeset_filter", changesetFilterCallback)
+	util.ExportFuncIIII(env, "go_changeset_conflict", changesetConflictCallback)
 	util.ExportFuncIIIII(env, "go_trace", traceCallback)
 	util.ExportFuncIIIIII(env, "go_autovacuum_pages", autoVacuumCallback)
 	util.ExportFuncIIIIIII(env, "go_authorizer", authorizerCallback)
@@ -1,4 +1,6 @@
 #include <stddef.h>
+#include <stdint.h>
+#include <string.h>
 
 #include "sqlite3.h"
 
@@ -48,6 +50,149 @@ int sqlite3_columns_go(sqlite3_stmt *stmt, int nCol, char *aType,
   return rc;
 }
 
+struct sqlite3_batch {
+  uint8_t *buf;
+  int cap;
+  int len;
+  int rows;
+};
+
+static int batch_reserve(struct sqlite3_batch *b, sqlite3_int64 n) {
+  if (b->len + n <= b->cap) return SQLITE_OK;
+  sqlite3_int64 cap = 2 * (sqlite3_int64)b->cap;
+  if (cap < b->len + n) cap = b->len + n;
+  if (cap < 4096) cap = 4096;
+  if (cap > 0x7fffffff) return SQLITE_TOOBIG;
+  uint8_t *buf = sqlite3_realloc64(b->buf, cap);
+  if (buf == NULL) return SQLITE_NOMEM;
+  b->buf = buf;
+  b->cap = cap;
+  return SQLITE_OK;
+}
+
+// Steps up to nRow rows, or until nByte bytes are used,
+// serializing each value as a type byte followed by:
+// 8 bytes for INTEGER and FLOAT,
+// a 4 byte length and the data for TEXT and BLOB,
+// nothing for NULL.
+int sqlite3_step_batch_go(sqlite3_stmt *stmt, int nRow, int nByte,
+                          struct sqlite3_batch *b) {
+  int nCol = sqlite3_column_count(stmt);
+  b->len = 0;
+  b->rows = 0;
+  while (b->rows < nRow && b->len < nByte) {
+    int rc = sqlite3_step(stmt);
+    if (rc != SQLITE_ROW) return rc;
+    for (int i = 0; i < nCol; ++i) {
+      int type = sqlite3_column_type(stmt, i);
+      const void *ptr = NULL;
+      int len = 0;
+      switch (type) {
+        case SQLITE_TEXT:
+          ptr = sqlite3_column_text(stmt, i);
+          break;
+        case SQLITE_BLOB:
+          ptr = sqlite3_column_blob(stmt, i);
+          break;
+      }
+      if (ptr != NULL) {
+        len = sqlite3_column_bytes(stmt, i);
+      } else if (type == SQLITE_TEXT || type == SQLITE_BLOB) {
+        rc = sqlite3_errcode(sqlite3_db_handle(stmt));
+        if (rc != SQLITE_ROW && rc != SQLITE_DONE && rc != SQLITE_OK) return rc;
+      }
+
+      rc = batch_reserve(b, 1 + 8 + len);
+      if (rc != SQLITE_OK) return rc;
+      uint8_t *p = b->buf + b->len;
+      *p++ = type;
+      switch (type) {
+        case SQLITE_INTEGER: {
+          sqlite3_int64 v = sqlite3_column_int64(stmt, i);
+          memcpy(p, &v, 8);
+          p += 8;
+          break;
+        }
+        case SQLITE_FLOAT: {
+          double v = sqlite3_column_double(stmt, i);
+          memcpy(p, &v, 8);
+          p += 8;
+          break;
+        }
+        case SQLITE_TEXT:
+        case SQLITE_BLOB:
+          memcpy(p, &len, 4);
+          p += 4;
+          if (len) memcpy(p, ptr, len);
+          p += len;
+          break;
+      }
+      b->len = p - b->buf;
+    }
+    b->rows++;
+  }
+  return SQLITE_ROW;
+}
+
+// Binds and executes up to nRow rows of nParam parameters,
+// serialized as in sqlite3_step_batch_go.
+// Stops at the first row that fails, setting *pDone to its index.
+// Bindings are always cleared, as they point into buf.
+int sqlite3_exec_batch_go(sqlite3_stmt *stmt, int nRow, int nParam,
+                          const uint8_t *buf, int *pDone) {
+  int rc = SQLITE_OK;
+  int row;
+  for (row = 0; row < nRow; ++row) {
+    for (int i = 1; i <= nParam && rc == SQLITE_OK; ++i) {
+      int type = *buf++;
+      switch (type) {
+        case SQLITE_INTEGER: {
+          sqlite3_int64 v;
+          memcpy(&v, buf, 8);
+          buf += 8;
+          rc = sqlite3_bind_int64(stmt, i, v);
+          break;
+        }
+        case SQLITE_FLOAT: {
+          double v;
+          memcpy(&v, buf, 8);
+          buf += 8;
+          rc = sqlite3_bind_double(stmt, i, v);
+          break;
+        }
+        case SQLITE_TEXT:
+        case SQLITE_BLOB: {
+          int len;
+          memcpy(&len, buf, 4);
+          buf += 4;
+          if (type == SQLITE_TEXT) {
+            rc = sqlite3_bind_text64(stmt, i, (const char *)buf, len,
+                                     SQLITE_STATIC, SQLITE_UTF8);
+          } else {
+            rc = sqlite3_bind_blob64(stmt, i, buf, len, SQLITE_STATIC);
+          }
+          buf += len;
+          break;
+        }
+        default:  // SQLITE_NULL
+          rc = sqlite3_bind_null(stmt, i);
+          break;
+      }
+    }
+    if (rc != SQLITE_OK) break;
+    while (sqlite3_step(stmt) == SQLITE_ROW);
+    rc = sqlite3_reset(stmt);
+    if (rc != SQLITE_OK) break;
+  }
+  sqlite3_clear_bindings(stmt);
+  *pDone = row;
+  return rc;
+}
+
+static_assert(offsetof(struct sqlite3_batch, buf) == 0, "Unexpected offset");
+static_assert(offsetof(struct sqlite3_batch, cap) == 4, "Unexpected offset");
+static_assert(offsetof(struct sqlite3_batch, len) == 8, "Unexpected offset");
+static_assert(offsetof(struct sqlite3_batch, rows) == 12, "Unexpected offset");
 static_assert(offsetof(union sqlite3_data, i) == 0, "Unexpected offset");
 static_assert(offsetof(union sqlite3_data, d) == 0, "Unexpected offset");
 static_assert(offsetof(union sqlite3_data, ptr) == 0, "Unexpected offset");
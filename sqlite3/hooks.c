@@ -9,6 +9,8 @@ int go_busy_timeout(int count, int tmout);
 int go_commit_hook(void *);
 void go_rollback_hook(void *);
 void go_update_hook(void *, int, char const *, char const *, sqlite3_int64);
+void go_preupdate_hook(void *, sqlite3 *, int, char const *, char const *,
+                       sqlite3_int64, sqlite3_int64);
 int go_wal_hook(void *, sqlite3 *, const char *, int);
 int go_trace(unsigned, void *, void *, void *);
 int go_authorizer(void *, int, const char *, const char *, const char *,
@@ -39,6 +41,10 @@ void sqlite3_update_hook_go(sqlite3 *db, bool enable) {
   sqlite3_update_hook(db, enable ? go_update_hook : NULL, /*arg=*/db);
 }
 
+void sqlite3_preupdate_hook_go(sqlite3 *db, bool enable) {
+  sqlite3_preupdate_hook(db, enable ? go_preupdate_hook : NULL, /*arg=*/NULL);
+}
+
 void sqlite3_wal_hook_go(sqlite3 *db, bool enable) {
   sqlite3_wal_hook(db, enable ? go_wal_hook : NULL, /*arg=*/NULL);
 }
@@ -16,6 +16,7 @@
 #include "hooks.c"
 #include "pointer.c"
 #include "result.c"
+#include "session.c"
 #include "time.c"
 #include "vfs.c"
 #include "vtab.c"
@@ -0,0 +1,18 @@
+#include <stdbool.h>
+#include <stddef.h>
+
+#include "include.h"
+#include "sqlite3.h"
+
+int go_changeset_filter(go_handle, const char *);
+int go_changeset_conflict(go_handle, int, sqlite3_changeset_iter *);
+
+int sqlite3changeset_apply_go(sqlite3 *db, int nChangeset, void *pChangeset,
+                              go_handle app, bool filter) {
+  int rc = sqlite3changeset_apply_v2(
+      db, nChangeset, pChangeset, filter ? go_changeset_filter : NULL,
+      go_changeset_conflict, app, /*ppRebase=*/NULL, /*pnRebase=*/NULL,
+      /*flags=*/0);
+  go_destroy(app);
+  return rc;
+}
@@ -24,14 +24,13 @@
 #define SQLITE_ENABLE_ATOMIC_WRITE
 #define SQLITE_ENABLE_BATCH_ATOMIC_WRITE
 #define SQLITE_ENABLE_COLUMN_METADATA
+#define SQLITE_ENABLE_DBPAGE_VTAB
 #define SQLITE_ENABLE_SETLK_TIMEOUT 2
 #define SQLITE_ENABLE_STAT4 1
-
-// We have our own memdb VFS.
-// To avoid interactions between the two,
-// omit sqlite3_serialize/sqlite3_deserialize,
-// which we also don't wrap.
-#define SQLITE_OMIT_DESERIALIZE
+#define SQLITE_ENABLE_PREUPDATE_HOOK
+#define SQLITE_ENABLE_SESSION
+#define SQLITE_ENABLE_SNAPSHOT
+#define SQLITE_ENABLE_STMT_SCANSTATUS
 
 // Amalgamated Extensions
 
@@ -89,6 +89,12 @@ struct go_file {
   go_handle handle;
 };
 
+// SQLite's own memdb VFS, used by sqlite3_deserialize.
+// It's only found while deserializing,
+// so it doesn't shadow a Go VFS with the same name.
+static sqlite3_vfs *memdb_vfs;
+static bool memdb_reopen;
+
 sqlite3_vfs *sqlite3_vfs_find(const char *zVfsName) {
   // The default VFS.
   if (!zVfsName || !strcmp(zVfsName, "os")) {
@@ -110,6 +116,11 @@ sqlite3_vfs *sqlite3_vfs_find(const char *zVfsName) {
     return &os_vfs;
   }
 
+  // Are we reopening a schema as a memdb database?
+  if (memdb_reopen && !strcmp(zVfsName, "memdb")) {
+    return memdb_vfs;
+  }
+
   // Check if a Go VFS exists.
   if (!go_vfs_find(zVfsName)) {
     return NULL;
@@ -159,6 +170,34 @@ sqlite3_vfs *sqlite3_vfs_find(const char *zVfsName) {
   return go_vfs_list;
 }
 
+int sqlite3_vfs_register(sqlite3_vfs *pVfs, int makeDflt) {
+  // Go handles VFS registration.
+  // Only SQLite's memdb VFS registers itself from C.
+  if (makeDflt || strcmp(pVfs->zName, "memdb")) {
+    return SQLITE_MISUSE;
+  }
+  memdb_vfs = pVfs;
+  return SQLITE_OK;
+}
+
+int sqlite3_vfs_unregister(sqlite3_vfs *pVfs) {
+  if (pVfs != memdb_vfs) {
+    return SQLITE_MISUSE;
+  }
+  memdb_vfs = NULL;
+  return SQLITE_OK;
+}
+
+int sqlite3_deserialize_go(sqlite3 *db, const char *zSchema,
+                           unsigned char *pData, sqlite3_int64 szDb,
+                           unsigned mFlags) {
+  memdb_reopen = true;
+  int rc = sqlite3_deserialize(db, zSchema, pData, szDb, szDb,
+                               mFlags | SQLITE_DESERIALIZE_FREEONCLOSE);
+  memdb_reopen = false;
+  return rc;
+}
+
 int localtime_s(struct tm *const pTm, time_t const *const pTime) {
   return go_localtime(pTm, (sqlite3_int64)*pTime);
 }
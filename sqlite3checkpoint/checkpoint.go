@@ -0,0 +1,260 @@
+// Package sqlite3checkpoint runs WAL checkpoints in the background.
+//
+// A [Manager] owns a dedicated connection to a database in WAL mode,
+// and periodically runs PASSIVE checkpoints on it,
+// escalating to RESTART and TRUNCATE checkpoints
+// when the WAL grows past configurable thresholds.
+//
+// This keeps checkpoints out of the commit path of writers.
+// Writers should disable automatic checkpoints,
+// which [Manager.WALHook] does,
+// while also waking the manager when the WAL grows large:
+//
+//	m, err := sqlite3checkpoint.Open("file:demo.db", nil)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer m.Close()
+//	go m.Run(ctx)
+//
+//	db.WALHook(m.WALHook)
+//
+// https://sqlite.org/wal.html#ckpt
+package sqlite3checkpoint
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ncruces/go-sqlite3"
+)
+
+// Options configure a [Manager].
+type Options struct {
+	// Interval is the time between checkpoints.
+	// The default is 1 second.
+	Interval time.Duration
+	// RestartPages is the WAL size, in pages, at which
+	// a RESTART checkpoint is run, which waits for readers
+	// so that the next writer can reuse the WAL from the beginning.
+	// The default is 4000; negative disables RESTART checkpoints.
+	RestartPages int
+	// TruncatePages is the WAL size, in pages, at which
+	// a TRUNCATE checkpoint is run, which also
+	// truncates the WAL file to zero bytes.
+	// The default is 16000; negative disables TRUNCATE checkpoints.
+	TruncatePages int
+	// BusyTimeout is how long RESTART and TRUNCATE checkpoints
+	// wait for readers and writers.
+	// The default is 1 second.
+	BusyTimeout time.Duration
+	// Checkpointed, if not nil, is called after every checkpoint
+	// that runs, even if it couldn't complete, with the connection
+	// of the manager, and the results of [sqlite3.Conn.WALCheckpoint].
+	Checkpointed func(db *sqlite3.Conn, schema string, frames, backfilled int)
+}
+
+// Stats are the statistics of a [Manager].
+type Stats struct {
+	Checkpoints int64 // Checkpoints run, of any mode.
+	Restarts    int64 // RESTART checkpoints run.
+	Truncates   int64 // TRUNCATE checkpoints run.
+	Busy        int64 // Checkpoints that couldn't complete.
+
+	Frames       int                    // Frames in the WAL, after the last checkpoint.
+	Backfilled   int                    // Frames checkpointed into the database, after the last checkpoint.
+	LastMode     sqlite3.CheckpointMode // The mode of the last checkpoint.
+	LastTime     time.Time              // When the last checkpoint started.
+	LastDuration time.Duration          // How long the last checkpoint took.
+	LastError    error                  // The error of the last checkpoint, if any.
+}
+
+// Manager runs WAL checkpoints in the background.
+// A Manager is safe for concurrent use by multiple goroutines.
+type Manager struct {
+	opts Options
+	wake chan struct{}
+	quit chan struct{}
+
+	connMtx   sync.Mutex
+	conn      *sqlite3.Conn
+	restarted int // WAL size after the last RESTART.
+
+	statsMtx sync.Mutex
+	stats    Stats
+}
+
+// Open opens a dedicated connection to the SQLite database specified by name,
+// and creates a Manager that checkpoints it.
+// Call [Manager.Run] to start checkpointing.
+func Open(name string, opts *Options) (*Manager, error) {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	if o.Interval <= 0 {
+		o.Interval = time.Second
+	}
+	if o.RestartPages == 0 {
+		o.RestartPages = 4000
+	}
+	if o.TruncatePages == 0 {
+		o.TruncatePages = 16000
+	}
+	if o.BusyTimeout <= 0 {
+		o.BusyTimeout = time.Second
+	}
+
+	conn, err := sqlite3.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	err = errors.Join(
+		conn.BusyTimeout(o.BusyTimeout),
+		conn.WALAutoCheckpoint(0))
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &Manager{
+		opts: o,
+		conn: conn,
+		wake: make(chan struct{}, 1),
+		quit: make(chan struct{}),
+	}, nil
+}
+
+// Close stops the manager, and closes its connection.
+//
+// It is safe to close a nil or closed Manager.
+func (m *Manager) Close() error {
+	if m == nil {
+		return nil
+	}
+
+	m.connMtx.Lock()
+	defer m.connMtx.Unlock()
+	if m.conn == nil {
+		return nil
+	}
+	close(m.quit)
+	err := m.conn.Close()
+	m.conn = nil
+	return err
+}
+
+// Run checkpoints the database every interval,
+// or when woken by [Manager.WALHook],
+// until ctx is done, or the manager is closed.
+// Checkpoints in progress are interrupted when ctx is done.
+//
+// Errors are reported by [Manager.Stats], and don't stop Run.
+func (m *Manager) Run(ctx context.Context) {
+	t := time.NewTicker(m.opts.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.quit:
+			return
+		case <-t.C:
+		case <-m.wake:
+		}
+		m.Checkpoint(ctx)
+	}
+}
+
+// Checkpoint runs a PASSIVE checkpoint now,
+// escalating it to a RESTART or TRUNCATE checkpoint
+// if the WAL is past their thresholds.
+//
+// https://sqlite.org/c3ref/wal_checkpoint_v2.html
+func (m *Manager) Checkpoint(ctx context.Context) error {
+	m.connMtx.Lock()
+	defer m.connMtx.Unlock()
+	if m.conn == nil {
+		return sqlite3.MISUSE
+	}
+
+	old := m.conn.SetInterrupt(ctx)
+	defer m.conn.SetInterrupt(old)
+
+	frames, err := m.checkpoint(sqlite3.CHECKPOINT_PASSIVE)
+	if err != nil {
+		return err
+	}
+
+	var mode sqlite3.CheckpointMode
+	switch {
+	case frames == m.restarted:
+		// No writes since the last RESTART.
+		return nil
+	case m.opts.TruncatePages > 0 && frames >= m.opts.TruncatePages:
+		mode = sqlite3.CHECKPOINT_TRUNCATE
+	case m.opts.RestartPages > 0 && frames >= m.opts.RestartPages:
+		mode = sqlite3.CHECKPOINT_RESTART
+	default:
+		return nil
+	}
+
+	frames, err = m.checkpoint(mode)
+	if err == nil {
+		m.restarted = frames
+	}
+	return err
+}
+
+func (m *Manager) checkpoint(mode sqlite3.CheckpointMode) (frames int, err error) {
+	start := time.Now()
+	frames, backfilled, err := m.conn.WALCheckpoint("main", mode)
+	duration := time.Since(start)
+	if m.opts.Checkpointed != nil && (err == nil || errors.Is(err, sqlite3.BUSY)) {
+		m.opts.Checkpointed(m.conn, "main", frames, backfilled)
+	}
+
+	m.statsMtx.Lock()
+	defer m.statsMtx.Unlock()
+	s := &m.stats
+	s.Checkpoints++
+	switch mode {
+	case sqlite3.CHECKPOINT_RESTART:
+		s.Restarts++
+	case sqlite3.CHECKPOINT_TRUNCATE:
+		s.Truncates++
+	}
+	if errors.Is(err, sqlite3.BUSY) {
+		s.Busy++
+	}
+	s.Frames = frames
+	s.Backfilled = backfilled
+	s.LastMode = mode
+	s.LastTime = start
+	s.LastDuration = duration
+	s.LastError = err
+	return frames, err
+}
+
+// Stats returns the statistics of the manager.
+func (m *Manager) Stats() Stats {
+	m.statsMtx.Lock()
+	defer m.statsMtx.Unlock()
+	return m.stats
+}
+
+// WALHook is a callback for [sqlite3.Conn.WALHook].
+// Registering it disables automatic checkpoints on the connection,
+// and wakes the manager when the WAL reaches the RESTART threshold.
+func (m *Manager) WALHook(db *sqlite3.Conn, schema string, pages int) error {
+	if m.opts.RestartPages > 0 && pages >= m.opts.RestartPages ||
+		m.opts.TruncatePages > 0 && pages >= m.opts.TruncatePages {
+		select {
+		case m.wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package sqlite3checkpoint_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ncruces/go-sqlite3"
+	_ "github.com/ncruces/go-sqlite3/embed"
+	_ "github.com/ncruces/go-sqlite3/internal/testcfg"
+	"github.com/ncruces/go-sqlite3/sqlite3checkpoint"
+)
+
+func TestManager(t *testing.T) {
+	t.Parallel()
+
+	name := "file:" + filepath.ToSlash(filepath.Join(t.TempDir(), "test.db")) +
+		"?_pragma=journal_mode(wal)"
+
+	db, err := sqlite3.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var checkpointed int
+	m, err := sqlite3checkpoint.Open(name, &sqlite3checkpoint.Options{
+		Interval:      time.Hour,
+		RestartPages:  30,
+		TruncatePages: 120,
+		Checkpointed: func(db *sqlite3.Conn, schema string, frames, backfilled int) {
+			checkpointed++
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	db.WALHook(m.WALHook)
+
+	insert := func(n int) {
+		t.Helper()
+		for range n {
+			err := db.Exec(`INSERT INTO test VALUES (randomblob(4096))`)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	err = db.Exec(`CREATE TABLE test (col)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	insert(2)
+
+	// Below the thresholds, checkpoints are PASSIVE.
+	ctx := context.Background()
+	if err := m.Checkpoint(ctx); err != nil {
+		t.Fatal(err)
+	}
+	s := m.Stats()
+	if s.Checkpoints != 1 || s.LastMode != sqlite3.CHECKPOINT_PASSIVE {
+		t.Errorf("got %d checkpoints, mode %d", s.Checkpoints, s.LastMode)
+	}
+	if s.Frames == 0 || s.Frames != s.Backfilled {
+		t.Errorf("got %d frames, %d backfilled", s.Frames, s.Backfilled)
+	}
+	if checkpointed != 1 {
+		t.Errorf("got %d callbacks, want 1", checkpointed)
+	}
+
+	// The WAL is past the RESTART threshold.
+	insert(15)
+	if err := m.Checkpoint(ctx); err != nil {
+		t.Fatal(err)
+	}
+	s = m.Stats()
+	if s.Restarts != 1 || s.LastMode != sqlite3.CHECKPOINT_RESTART {
+		t.Errorf("got %d restarts, mode %d", s.Restarts, s.LastMode)
+	}
+
+	// Without writes, there's nothing to escalate.
+	if err := m.Checkpoint(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if s := m.Stats(); s.Restarts != 1 || s.LastMode != sqlite3.CHECKPOINT_PASSIVE {
+		t.Errorf("got %d restarts, mode %d", s.Restarts, s.LastMode)
+	}
+
+	// The WAL is past the TRUNCATE threshold.
+	insert(60)
+	if err := m.Checkpoint(ctx); err != nil {
+		t.Fatal(err)
+	}
+	s = m.Stats()
+	if s.Truncates != 1 || s.LastMode != sqlite3.CHECKPOINT_TRUNCATE {
+		t.Errorf("got %d truncates, mode %d", s.Truncates, s.LastMode)
+	}
+	if s.Frames != 0 {
+		t.Errorf("got %d frames, want 0", s.Frames)
+	}
+
+	// In the background, the hook wakes the manager.
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		m.Run(ctx)
+		close(done)
+	}()
+
+	insert(20)
+	for deadline := time.Now().Add(10 * time.Second); ; {
+		s = m.Stats()
+		if s.Restarts > 1 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if s.Restarts < 2 {
+		t.Errorf("got %d restarts", s.Restarts)
+	}
+	if s.LastDuration <= 0 || s.LastTime.IsZero() {
+		t.Errorf("got %v, %v", s.LastDuration, s.LastTime)
+	}
+
+	cancel()
+	<-done
+
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Checkpoint(context.Background()); err == nil {
+		t.Error("want error")
+	}
+}
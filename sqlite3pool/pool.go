@@ -0,0 +1,361 @@
+// Package sqlite3pool provides a pool of SQLite connections
+// for code that uses [sqlite3.Conn] directly.
+//
+// A pool keeps a single read-write connection,
+// and a set of read-only connections:
+//
+//	pool, err := sqlite3pool.Open("file:demo.db?_pragma=journal_mode(wal)", 4)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer pool.Close()
+//
+//	conn, err := pool.GetReadOnly(ctx)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer pool.Put(conn)
+//
+// Concurrent readers require a database in [WAL] mode.
+//
+// [WAL]: https://sqlite.org/wal.html
+package sqlite3pool
+
+import (
+	"context"
+	"net/url"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncruces/go-sqlite3"
+)
+
+// Pool is a pool of connections to an SQLite database.
+// A Pool is safe for concurrent use by multiple goroutines.
+type Pool struct {
+	init    func(*sqlite3.Conn) error
+	term    func(*sqlite3.Conn) error
+	name    string
+	pragmas bool
+
+	// Idle connections, or nil tokens for connections to be opened.
+	writer  chan *sqlite3.Conn
+	readers chan *sqlite3.Conn
+
+	mtx    sync.Mutex
+	conns  map[*sqlite3.Conn]connState
+	closed bool
+	done   chan struct{}
+}
+
+type connState struct {
+	readOnly bool
+	idle     bool
+}
+
+// Open opens a pool of connections to the SQLite database specified by name,
+// with a single read-write connection, and up to readers read-only connections.
+// If readers is zero, [runtime.GOMAXPROCS] is used.
+//
+// Open accepts zero, one, or two callbacks (nil callbacks are ignored).
+// The first callback is called when the pool opens a new connection.
+// The second callback is called before the pool closes a connection.
+// As with the database/sql driver,
+// if no PRAGMAs are specified in name, a busy timeout of 1 minute is set.
+func Open(name string, readers int, fn ...func(*sqlite3.Conn) error) (*Pool, error) {
+	if len(fn) > 2 || readers < 0 {
+		return nil, sqlite3.MISUSE
+	}
+	if readers == 0 {
+		readers = runtime.GOMAXPROCS(0)
+	}
+
+	p := &Pool{
+		name:    name,
+		writer:  make(chan *sqlite3.Conn, 1),
+		readers: make(chan *sqlite3.Conn, readers),
+		conns:   map[*sqlite3.Conn]connState{},
+		done:    make(chan struct{}),
+	}
+	if len(fn) > 1 {
+		p.term = fn[1]
+	}
+	if len(fn) > 0 {
+		p.init = fn[0]
+	}
+	if strings.HasPrefix(name, "file:") {
+		if _, after, ok := strings.Cut(name, "?"); ok {
+			query, err := url.ParseQuery(after)
+			if err != nil {
+				return nil, err
+			}
+			p.pragmas = query.Has("_pragma")
+		}
+	}
+
+	// Open the writer now, to create the database and catch errors early.
+	c, err := p.open(context.Background(), false)
+	if err != nil {
+		return nil, err
+	}
+	p.writer <- c
+	for range readers {
+		p.readers <- nil
+	}
+	return p, nil
+}
+
+// Get gets the read-write connection from the pool,
+// waiting until it's available, or ctx is done.
+// The connection is interrupted when ctx is done, see [sqlite3.Conn.SetInterrupt].
+// Call [Pool.Put] to return the connection to the pool.
+func (p *Pool) Get(ctx context.Context) (*sqlite3.Conn, error) {
+	return p.get(ctx, p.writer, false)
+}
+
+// GetReadOnly gets a read-only connection from the pool,
+// waiting until one is available, or ctx is done.
+// The connection is interrupted when ctx is done, see [sqlite3.Conn.SetInterrupt].
+// Call [Pool.Put] to return the connection to the pool.
+func (p *Pool) GetReadOnly(ctx context.Context) (*sqlite3.Conn, error) {
+	return p.get(ctx, p.readers, true)
+}
+
+func (p *Pool) get(ctx context.Context, ch chan *sqlite3.Conn, readOnly bool) (*sqlite3.Conn, error) {
+	if p.isClosed() {
+		return nil, sqlite3.MISUSE
+	}
+
+	var c *sqlite3.Conn
+	select {
+	case c = <-ch:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-p.done:
+		return nil, sqlite3.MISUSE
+	}
+
+	if c == nil {
+		if p.isClosed() {
+			return nil, sqlite3.MISUSE
+		}
+		var err error
+		c, err = p.open(ctx, readOnly)
+		if err != nil {
+			ch <- nil
+			return nil, err
+		}
+	} else {
+		p.mtx.Lock()
+		closed := p.closed
+		p.conns[c] = connState{readOnly: readOnly}
+		p.mtx.Unlock()
+		if closed {
+			p.close(c)
+			return nil, sqlite3.MISUSE
+		}
+	}
+	c.SetInterrupt(ctx)
+	return c, nil
+}
+
+// Put returns a connection to the pool.
+//
+// Put resets the connection:
+// it closes all its prepared statements,
+// rolls back any open transaction,
+// and clears its interrupt context.
+// If the connection can't be reset, it's closed.
+// Put panics if the connection is not in use from this pool.
+func (p *Pool) Put(c *sqlite3.Conn) {
+	p.mtx.Lock()
+	state, ok := p.conns[c]
+	if ok && !state.idle {
+		p.conns[c] = connState{readOnly: state.readOnly, idle: true}
+	}
+	closed := p.closed
+	p.mtx.Unlock()
+	if !ok {
+		panic("sqlite3pool: connection not from this pool")
+	}
+	if state.idle {
+		panic("sqlite3pool: connection already returned to the pool")
+	}
+
+	var err error
+	if !closed {
+		err = reset(c)
+	}
+	p.release(c, state.readOnly, err != nil)
+}
+
+// release sends an idle connection back to its channel,
+// or closes it, if bad or if the pool was closed.
+// Sending holds the mutex, so Close can't miss the connection.
+func (p *Pool) release(c *sqlite3.Conn, readOnly, bad bool) {
+	ch := p.writer
+	if readOnly {
+		ch = p.readers
+	}
+
+	p.mtx.Lock()
+	closed := p.closed
+	switch {
+	case closed:
+	case bad:
+		ch <- nil
+	default:
+		ch <- c
+	}
+	p.mtx.Unlock()
+
+	if closed || bad {
+		p.close(c)
+	}
+}
+
+// Idle calls fn for each idle connection in the pool.
+// The connections are taken from the pool for the duration of the call,
+// and must not be retained by fn.
+func (p *Pool) Idle(fn func(*sqlite3.Conn)) {
+	for i, ch := range [...]chan *sqlite3.Conn{p.writer, p.readers} {
+		var conns []*sqlite3.Conn
+	take:
+		for range cap(ch) {
+			select {
+			case c := <-ch:
+				conns = append(conns, c)
+			default:
+				break take
+			}
+		}
+
+		for _, c := range conns {
+			if c != nil && !p.isClosed() {
+				fn(c)
+			}
+		}
+		for _, c := range conns {
+			if c == nil {
+				ch <- nil
+				continue
+			}
+			p.release(c, i > 0, false)
+		}
+	}
+}
+
+// Close closes all idle connections in the pool.
+// Connections in use are closed when returned to the pool.
+// Calls to [Pool.Get] and [Pool.GetReadOnly] waiting for a connection
+// return [sqlite3.MISUSE].
+func (p *Pool) Close() error {
+	p.mtx.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.done)
+	}
+	p.mtx.Unlock()
+
+	var err error
+	for _, ch := range [...]chan *sqlite3.Conn{p.writer, p.readers} {
+	drain:
+		for {
+			select {
+			case c := <-ch:
+				if e := p.close(c); err == nil {
+					err = e
+				}
+			default:
+				break drain
+			}
+		}
+	}
+	return err
+}
+
+func (p *Pool) isClosed() bool {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.closed
+}
+
+func (p *Pool) open(ctx context.Context, readOnly bool) (_ *sqlite3.Conn, err error) {
+	c, err := sqlite3.OpenContext(ctx, p.name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			c.Close()
+		}
+	}()
+
+	old := c.SetInterrupt(ctx)
+	defer c.SetInterrupt(old)
+
+	if !p.pragmas {
+		err = c.BusyTimeout(time.Minute)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if p.init != nil {
+		err = p.init(c)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if readOnly {
+		err = c.Exec(`PRAGMA query_only=1`)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p.mtx.Lock()
+	p.conns[c] = connState{readOnly: readOnly}
+	p.mtx.Unlock()
+	return c, nil
+}
+
+func (p *Pool) close(c *sqlite3.Conn) error {
+	if c == nil {
+		return nil
+	}
+
+	p.mtx.Lock()
+	delete(p.conns, c)
+	p.mtx.Unlock()
+
+	var err error
+	if p.term != nil {
+		err = p.term(c)
+	}
+	if e := c.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+func reset(c *sqlite3.Conn) error {
+	c.SetInterrupt(context.Background())
+
+	var stmts []*sqlite3.Stmt
+	c.Stmts()(func(s *sqlite3.Stmt) bool {
+		stmts = append(stmts, s)
+		return true
+	})
+	for _, s := range stmts {
+		// Close returns the last error from the statement,
+		// but always finalizes it.
+		s.Close()
+	}
+
+	if !c.GetAutocommit() {
+		return c.Exec(`ROLLBACK`)
+	}
+	return nil
+}
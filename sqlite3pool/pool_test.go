@@ -0,0 +1,210 @@
+package sqlite3pool_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ncruces/go-sqlite3"
+	_ "github.com/ncruces/go-sqlite3/embed"
+	_ "github.com/ncruces/go-sqlite3/internal/testcfg"
+	"github.com/ncruces/go-sqlite3/sqlite3pool"
+)
+
+func TestPool(t *testing.T) {
+	t.Parallel()
+
+	name := "file:" + filepath.ToSlash(filepath.Join(t.TempDir(), "test.db")) +
+		"?_pragma=busy_timeout(10000)&_pragma=journal_mode(wal)"
+
+	var inits, terms int
+	var mtx sync.Mutex
+	pool, err := sqlite3pool.Open(name, 2,
+		func(c *sqlite3.Conn) error {
+			mtx.Lock()
+			defer mtx.Unlock()
+			inits++
+			return nil
+		},
+		func(c *sqlite3.Conn) error {
+			mtx.Lock()
+			defer mtx.Unlock()
+			terms++
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	w, err := pool.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.Exec(`CREATE TABLE test (col); INSERT INTO test VALUES (1)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Leave a statement and a transaction open.
+	err = w.Exec(`BEGIN; INSERT INTO test VALUES (2)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = w.Prepare(`SELECT * FROM test`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.Put(w)
+
+	// There's a single writer.
+	w1, err := pool.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	timeout, cancel := context.WithTimeout(ctx, time.Millisecond)
+	defer cancel()
+	_, err = pool.Get(timeout)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want context.DeadlineExceeded", err)
+	}
+
+	// The writer was reset.
+	if w1 != w {
+		t.Error("want same connection")
+	}
+	if !w1.GetAutocommit() {
+		t.Error("want autocommit")
+	}
+	w1.Stmts()(func(s *sqlite3.Stmt) bool {
+		t.Errorf("statement not closed: %v", s)
+		return true
+	})
+	pool.Put(w1)
+
+	// A connection can only be returned once.
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("want panic")
+			}
+		}()
+		pool.Put(w1)
+	}()
+
+	r1, err := pool.GetReadOnly(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, err := pool.GetReadOnly(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stmt, _, err := r1.Prepare(`SELECT count(*) FROM test`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stmt.Step() {
+		t.Fatal(stmt.Err())
+	}
+	if got := stmt.ColumnInt(0); got != 1 {
+		t.Errorf("got %d, want 1", got)
+	}
+	stmt.Close()
+
+	err = r2.Exec(`INSERT INTO test VALUES (3)`)
+	if !errors.Is(err, sqlite3.READONLY) {
+		t.Errorf("got %v, want sqlite3.READONLY", err)
+	}
+
+	// Close wakes up a blocked Get.
+	w2, err := pool.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocked := make(chan error)
+	go func() {
+		_, err := pool.Get(ctx)
+		blocked <- err
+	}()
+
+	pool.Put(r1)
+	err = pool.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-blocked; !errors.Is(err, sqlite3.MISUSE) {
+		t.Errorf("got %v, want sqlite3.MISUSE", err)
+	}
+	pool.Put(r2)
+	pool.Put(w2)
+
+	_, err = pool.Get(ctx)
+	if !errors.Is(err, sqlite3.MISUSE) {
+		t.Errorf("got %v, want sqlite3.MISUSE", err)
+	}
+	if inits != 3 || terms != 3 {
+		t.Errorf("got %d inits and %d terms, want 3", inits, terms)
+	}
+}
+
+func TestPool_Close(t *testing.T) {
+	t.Parallel()
+
+	name := "file:" + filepath.ToSlash(filepath.Join(t.TempDir(), "test.db")) +
+		"?_pragma=journal_mode(wal)"
+
+	var opened, closed sync.Map
+	pool, err := sqlite3pool.Open(name, 4,
+		func(c *sqlite3.Conn) error {
+			opened.Store(c, true)
+			return nil
+		},
+		func(c *sqlite3.Conn) error {
+			closed.Store(c, true)
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	conns := make([]*sqlite3.Conn, 5)
+	conns[0], err = pool.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < len(conns); i++ {
+		conns[i], err = pool.GetReadOnly(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Connections returned while closing are closed.
+	var wg sync.WaitGroup
+	for _, c := range conns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pool.Put(c)
+		}()
+	}
+	err = pool.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+
+	opened.Range(func(c, _ any) bool {
+		if _, ok := closed.Load(c); !ok {
+			t.Errorf("connection not closed: %v", c)
+		}
+		return true
+	})
+}
@@ -0,0 +1,373 @@
+// Package sqlite3stats collects statistics from SQLite connections.
+//
+// A [Collector] samples the status of the connections registered with it,
+// and counts their commits and rollbacks.
+// A connection can't be used concurrently, so its status is sampled
+// from its own commit and rollback hooks, by calling [Collector.Sample],
+// or periodically by [Collector.Run], for connections that are idle:
+//
+//	stats := sqlite3stats.New(time.Second)
+//	stats.Publish("sqlite")
+//
+//	pool, err := sqlite3pool.Open("file:demo.db", 4, stats.Register, stats.Unregister)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	go stats.Run(ctx, pool.Idle)
+//
+// The collector only observes connections:
+// it doesn't change their checkpoint settings.
+// To track the size of the WAL, call [Collector.WALHook]
+// from the WAL hook of the connection,
+// and report checkpoints with [Collector.WALCheckpoint].
+//
+// https://sqlite.org/c3ref/db_status.html
+package sqlite3stats
+
+import (
+	"context"
+	"expvar"
+	"sync"
+	"time"
+
+	"github.com/ncruces/go-sqlite3"
+)
+
+// Snapshot is a point-in-time view of the statistics of a [Collector].
+//
+// Counters are cumulative, and include connections no longer registered.
+// Memory usage only includes the currently registered connections.
+type Snapshot struct {
+	Connections int // Registered connections.
+
+	Commits   int64 // Transactions committed.
+	Rollbacks int64 // Transactions rolled back.
+	Changes   int64 // Rows inserted, updated or deleted.
+
+	CacheHit   int64 // Page cache hits.
+	CacheMiss  int64 // Page cache misses.
+	CacheWrite int64 // Dirty pages written to the database file.
+	CacheSpill int64 // Dirty pages written mid-transaction.
+
+	CacheUsed  int64 // Bytes of heap used by page caches.
+	SchemaUsed int64 // Bytes of heap used to store schemas.
+	StmtUsed   int64 // Bytes of heap used by prepared statements.
+
+	WALCommits  int64 // Successful commits to databases in WAL mode.
+	WALPages    int64 // Pages in the WAL after the last commit or checkpoint, across databases.
+	MaxWALPages int64 // Largest WAL seen, in pages.
+}
+
+// Collector collects statistics from SQLite connections.
+// A Collector is safe for concurrent use by multiple goroutines.
+type Collector struct {
+	interval time.Duration
+
+	mtx   sync.Mutex
+	base  Snapshot // Counters of unregistered connections.
+	conns map[*sqlite3.Conn]*connStats
+	wal   map[string]int64 // Pages in the WAL of each database.
+}
+
+type connStats struct {
+	Snapshot
+	sampled time.Time
+	wal     map[string]bool // Databases whose WAL it reported.
+
+	// A commit attempt is only known to have succeeded
+	// once the data version of the main database changes.
+	pending bool
+	version uint32
+}
+
+// New creates a Collector that samples the status of each connection
+// at most once per interval.
+func New(interval time.Duration) *Collector {
+	return &Collector{
+		interval: interval,
+		conns:    map[*sqlite3.Conn]*connStats{},
+		wal:      map[string]int64{},
+	}
+}
+
+// Register starts collecting statistics from conn.
+// It replaces the commit and rollback hooks of conn.
+//
+// The commit hook runs before a commit is attempted,
+// so a commit is only counted once it's known to have succeeded:
+// when a later hook of conn runs, or when conn is sampled.
+// Commits that only change attached databases aren't counted.
+// Call [Collector.Unregister] before closing conn.
+//
+// Register and Unregister can be used as the callbacks of
+// [github.com/ncruces/go-sqlite3/sqlite3pool.Open].
+func (c *Collector) Register(conn *sqlite3.Conn) error {
+	c.mtx.Lock()
+	c.conns[conn] = &connStats{}
+	c.mtx.Unlock()
+
+	conn.CommitHook(func() bool {
+		version := c.settle(conn)
+		c.update(conn, func(s *connStats) {
+			s.pending = true
+			s.version = version
+		})
+		c.sample(conn, false)
+		return true
+	})
+	conn.RollbackHook(func() {
+		c.settle(conn)
+		c.update(conn, func(s *connStats) { s.Rollbacks++ })
+		c.sample(conn, false)
+	})
+	c.sample(conn, true)
+	return nil
+}
+
+// Unregister stops collecting statistics from conn,
+// and removes its hooks.
+func (c *Collector) Unregister(conn *sqlite3.Conn) error {
+	c.Sample(conn)
+	conn.CommitHook(nil)
+	conn.RollbackHook(nil)
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if s := c.conns[conn]; s != nil {
+		delete(c.conns, conn)
+		b := &c.base
+		b.Commits += s.Commits
+		b.Rollbacks += s.Rollbacks
+		b.Changes += s.Changes
+		b.CacheHit += s.CacheHit
+		b.CacheMiss += s.CacheMiss
+		b.CacheWrite += s.CacheWrite
+		b.CacheSpill += s.CacheSpill
+		b.WALCommits += s.WALCommits
+		b.MaxWALPages = max(b.MaxWALPages, s.MaxWALPages)
+
+		// Forget the WAL of databases no longer observed.
+		for name := range s.wal {
+			if !c.observed(name) {
+				delete(c.wal, name)
+			}
+		}
+	}
+	return nil
+}
+
+// observed reports if a registered connection reported the WAL of a database.
+// It must be called with the mutex held.
+func (c *Collector) observed(name string) bool {
+	for _, s := range c.conns {
+		if s.wal[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// Sample samples the status of conn now.
+// It must not be called concurrently with other uses of conn.
+func (c *Collector) Sample(conn *sqlite3.Conn) {
+	c.settle(conn)
+	c.sample(conn, true)
+}
+
+// settle counts the pending commit attempt of conn, if it succeeded,
+// and returns the current data version of its main database.
+func (c *Collector) settle(conn *sqlite3.Conn) uint32 {
+	var version uint32
+	if v, err := conn.FileControl("", sqlite3.FCNTL_DATA_VERSION); err == nil {
+		version = v.(uint32)
+	}
+	c.update(conn, func(s *connStats) {
+		if s.pending && s.version != version {
+			s.Commits++
+		}
+		s.pending = false
+	})
+	return version
+}
+
+// Run samples connections every interval,
+// which must be positive, until ctx is done.
+//
+// A connection can't be used concurrently, so every interval
+// Run calls idle, which should call sample for each registered connection
+// that is not in use, and must not be used until sample returns.
+// [github.com/ncruces/go-sqlite3/sqlite3pool.Pool.Idle] does this
+// for the idle connections of a pool.
+func (c *Collector) Run(ctx context.Context, idle func(sample func(*sqlite3.Conn))) error {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		idle(c.Sample)
+	}
+}
+
+func (c *Collector) sample(conn *sqlite3.Conn, force bool) {
+	now := time.Now()
+
+	c.mtx.Lock()
+	s := c.conns[conn]
+	if s == nil || !force && now.Sub(s.sampled) < c.interval {
+		c.mtx.Unlock()
+		return
+	}
+	s.sampled = now
+	c.mtx.Unlock()
+
+	status := func(op sqlite3.DBStatus) int64 {
+		cur, _, _ := conn.Status(op, false)
+		return int64(cur)
+	}
+	var n Snapshot
+	n.Changes = conn.TotalChanges()
+	n.CacheHit = status(sqlite3.DBSTATUS_CACHE_HIT)
+	n.CacheMiss = status(sqlite3.DBSTATUS_CACHE_MISS)
+	n.CacheWrite = status(sqlite3.DBSTATUS_CACHE_WRITE)
+	n.CacheSpill = status(sqlite3.DBSTATUS_CACHE_SPILL)
+	n.CacheUsed = status(sqlite3.DBSTATUS_CACHE_USED)
+	n.SchemaUsed = status(sqlite3.DBSTATUS_SCHEMA_USED)
+	n.StmtUsed = status(sqlite3.DBSTATUS_STMT_USED)
+
+	c.update(conn, func(s *connStats) {
+		s.Changes = n.Changes
+		s.CacheHit = n.CacheHit
+		s.CacheMiss = n.CacheMiss
+		s.CacheWrite = n.CacheWrite
+		s.CacheSpill = n.CacheSpill
+		s.CacheUsed = n.CacheUsed
+		s.SchemaUsed = n.SchemaUsed
+		s.StmtUsed = n.StmtUsed
+	})
+}
+
+// WALHook records a successful commit,
+// and the size of the WAL after it.
+// The WAL of a database is no longer reported
+// once all connections that reported it are unregistered.
+//
+// It doesn't checkpoint the database.
+// Since registering a WAL hook disables automatic checkpoints,
+// call it from a hook that checkpoints, like the one of
+// [github.com/ncruces/go-sqlite3/sqlite3checkpoint.Manager],
+// rather than registering it directly.
+func (c *Collector) WALHook(conn *sqlite3.Conn, schema string, pages int) error {
+	name := conn.Filename(schema).String()
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if s := c.conns[conn]; s != nil {
+		if s.pending {
+			s.pending = false
+			s.Commits++
+		}
+		s.WALCommits++
+		s.MaxWALPages = max(s.MaxWALPages, int64(pages))
+		// Connections to the same database share its WAL.
+		if s.wal == nil {
+			s.wal = map[string]bool{}
+		}
+		s.wal[name] = true
+		c.wal[name] = int64(pages)
+	}
+	return nil
+}
+
+// WALCheckpoint records the size of the WAL of a database
+// after a checkpoint, given the results of [sqlite3.Conn.WALCheckpoint].
+// A fully checkpointed WAL counts as empty,
+// since the next commit can restart it.
+// Unlike other methods, conn needs not be registered,
+// but the WAL of the database must have been reported by [Collector.WALHook].
+//
+// It can be used as the Checkpointed callback of
+// [github.com/ncruces/go-sqlite3/sqlite3checkpoint.Options].
+func (c *Collector) WALCheckpoint(conn *sqlite3.Conn, schema string, frames, backfilled int) {
+	name := conn.Filename(schema).String()
+	if backfilled >= frames {
+		frames = 0
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if _, ok := c.wal[name]; ok {
+		c.wal[name] = int64(frames)
+	}
+}
+
+func (c *Collector) update(conn *sqlite3.Conn, fn func(*connStats)) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if s := c.conns[conn]; s != nil {
+		fn(s)
+	}
+}
+
+// Snapshot returns the current statistics.
+func (c *Collector) Snapshot() Snapshot {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	n := c.base
+	n.Connections = len(c.conns)
+	for _, s := range c.conns {
+		n.Commits += s.Commits
+		n.Rollbacks += s.Rollbacks
+		n.Changes += s.Changes
+		n.CacheHit += s.CacheHit
+		n.CacheMiss += s.CacheMiss
+		n.CacheWrite += s.CacheWrite
+		n.CacheSpill += s.CacheSpill
+		n.CacheUsed += s.CacheUsed
+		n.SchemaUsed += s.SchemaUsed
+		n.StmtUsed += s.StmtUsed
+		n.WALCommits += s.WALCommits
+		n.MaxWALPages = max(n.MaxWALPages, s.MaxWALPages)
+	}
+	for _, pages := range c.wal {
+		n.WALPages += pages
+	}
+	return n
+}
+
+// Func returns an [expvar.Func] that reports
+// a consistent snapshot of the statistics of the collector,
+// as a JSON object.
+// Use [expvar.Publish] to export it,
+// or [Collector.Publish] to do both.
+func (c *Collector) Func() expvar.Func {
+	return func() any {
+		s := c.Snapshot()
+		return map[string]int64{
+			"connections":   int64(s.Connections),
+			"commits":       s.Commits,
+			"rollbacks":     s.Rollbacks,
+			"changes":       s.Changes,
+			"cache_hit":     s.CacheHit,
+			"cache_miss":    s.CacheMiss,
+			"cache_write":   s.CacheWrite,
+			"cache_spill":   s.CacheSpill,
+			"cache_used":    s.CacheUsed,
+			"schema_used":   s.SchemaUsed,
+			"stmt_used":     s.StmtUsed,
+			"wal_commits":   s.WALCommits,
+			"wal_pages":     s.WALPages,
+			"max_wal_pages": s.MaxWALPages,
+		}
+	}
+}
+
+// Publish exports the statistics of the collector
+// as an [expvar.Func] named name.
+// Like [expvar.Publish], it panics if the name is already registered.
+func (c *Collector) Publish(name string) {
+	expvar.Publish(name, c.Func())
+}
@@ -0,0 +1,271 @@
+package sqlite3stats_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ncruces/go-sqlite3"
+	_ "github.com/ncruces/go-sqlite3/embed"
+	_ "github.com/ncruces/go-sqlite3/internal/testcfg"
+	"github.com/ncruces/go-sqlite3/sqlite3pool"
+	"github.com/ncruces/go-sqlite3/sqlite3stats"
+)
+
+func TestCollector(t *testing.T) {
+	t.Parallel()
+
+	name := "file:" + filepath.ToSlash(filepath.Join(t.TempDir(), "test.db")) +
+		"?_pragma=journal_mode(wal)&_pragma=wal_autocheckpoint(10)"
+
+	db, err := sqlite3.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	stats := sqlite3stats.New(0)
+	err = stats.Register(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Exec(`CREATE TABLE test (col)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 20 {
+		err = db.Exec(`INSERT INTO test VALUES (randomblob(4096))`)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = db.Exec(`BEGIN; INSERT INTO test VALUES (1); ROLLBACK`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Registering doesn't disable automatic checkpoints.
+	nLog, _, err := db.WALCheckpoint("main", sqlite3.CHECKPOINT_PASSIVE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nLog >= 20 {
+		t.Errorf("got %d pages in the WAL", nLog)
+	}
+
+	s := stats.Snapshot()
+	if s.Connections != 1 {
+		t.Errorf("got %d connections", s.Connections)
+	}
+	if s.Commits != 21 || s.WALCommits != 0 {
+		t.Errorf("got %d commits, %d WAL commits", s.Commits, s.WALCommits)
+	}
+	if s.Rollbacks != 1 {
+		t.Errorf("got %d rollbacks", s.Rollbacks)
+	}
+	if s.Changes != 21 {
+		t.Errorf("got %d changes", s.Changes)
+	}
+	if s.CacheUsed == 0 || s.SchemaUsed == 0 {
+		t.Errorf("got %+v", s)
+	}
+
+	// Observe the WAL from a hook that checkpoints.
+	var last int
+	hook := func(db *sqlite3.Conn, schema string, pages int) error {
+		stats.WALHook(db, schema, pages)
+		last = pages
+		if pages >= 10 {
+			db.WALCheckpoint(schema, sqlite3.CHECKPOINT_PASSIVE)
+		}
+		return nil
+	}
+	db.WALHook(hook)
+	for range 20 {
+		err = db.Exec(`INSERT INTO test VALUES (randomblob(4096))`)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s = stats.Snapshot()
+	if s.Commits != 41 || s.WALCommits != 20 {
+		t.Errorf("got %d commits, %d WAL commits", s.Commits, s.WALCommits)
+	}
+	if s.MaxWALPages < 10 || s.MaxWALPages >= 20 {
+		t.Errorf("got %+v", s)
+	}
+
+	var m map[string]int64
+	err = json.Unmarshal([]byte(stats.Func().String()), &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["commits"] != 41 || m["connections"] != 1 {
+		t.Errorf("got %v", m)
+	}
+
+	// Connections to the same database share its WAL.
+	db2, err := sqlite3.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db2.Close()
+	err = stats.Register(db2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db2.WALHook(hook)
+	err = db2.Exec(`INSERT INTO test VALUES (1)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s = stats.Snapshot()
+	if s.WALPages != int64(last) {
+		t.Errorf("got %d WAL pages, want %d", s.WALPages, last)
+	}
+
+	// Checkpoints empty the WAL.
+	frames, backfilled, err := db.WALCheckpoint("main", sqlite3.CHECKPOINT_TRUNCATE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats.WALCheckpoint(db, "main", frames, backfilled)
+	if s := stats.Snapshot(); s.WALPages != 0 {
+		t.Errorf("got %d WAL pages, want 0", s.WALPages)
+	}
+	err = db2.Exec(`INSERT INTO test VALUES (1)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = stats.Unregister(db2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = stats.Unregister(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s = stats.Snapshot()
+	if s.Connections != 0 || s.Commits != 43 || s.CacheUsed != 0 || s.WALPages != 0 {
+		t.Errorf("got %+v", s)
+	}
+}
+
+func TestCollector_busy(t *testing.T) {
+	t.Parallel()
+
+	name := "file:" + filepath.ToSlash(filepath.Join(t.TempDir(), "test.db")) +
+		"?_pragma=journal_mode(delete)"
+
+	db1, err := sqlite3.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db1.Close()
+	db2, err := sqlite3.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db2.Close()
+
+	stats := sqlite3stats.New(0)
+	err = stats.Register(db1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db1.Exec(`CREATE TABLE test (col)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A reader makes the commit fail.
+	err = db2.Exec(`BEGIN; SELECT * FROM test`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db1.Exec(`BEGIN; INSERT INTO test VALUES (1)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db1.Exec(`COMMIT`)
+	if !errors.Is(err, sqlite3.BUSY) {
+		t.Fatalf("got %v, want BUSY", err)
+	}
+	stats.Sample(db1)
+	if s := stats.Snapshot(); s.Commits != 1 {
+		t.Errorf("got %d commits, want 1", s.Commits)
+	}
+
+	// Retry it.
+	err = db2.Exec(`COMMIT`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db1.Exec(`COMMIT`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats.Sample(db1)
+	if s := stats.Snapshot(); s.Commits != 2 || s.Rollbacks != 0 {
+		t.Errorf("got %d commits, %d rollbacks, want 2, 0", s.Commits, s.Rollbacks)
+	}
+}
+
+func TestCollector_Run(t *testing.T) {
+	t.Parallel()
+
+	name := "file:" + filepath.ToSlash(filepath.Join(t.TempDir(), "test.db")) +
+		"?_pragma=journal_mode(wal)"
+
+	stats := sqlite3stats.New(time.Millisecond)
+	pool, err := sqlite3pool.Open(name, 1, stats.Register, stats.Unregister)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	ctx := context.Background()
+	w, err := pool.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.Exec(`CREATE TABLE test (col); INSERT INTO test VALUES (1)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.Put(w)
+
+	// Read-only connections don't commit,
+	// so they're only sampled while idle.
+	r, err := pool.GetReadOnly(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := stats.Snapshot()
+	for range 10 {
+		err = r.Exec(`SELECT * FROM test`)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	pool.Put(r)
+
+	ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	err = stats.Run(ctx, pool.Idle)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v", err)
+	}
+
+	after := stats.Snapshot()
+	if after.Connections != 2 || after.CacheHit <= before.CacheHit {
+		t.Errorf("got %+v, then %+v", before, after)
+	}
+}
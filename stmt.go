@@ -1,8 +1,10 @@
 package sqlite3
 
 import (
+	"database/sql/driver"
 	"encoding/json"
 	"math"
+	"reflect"
 	"strconv"
 	"time"
 
@@ -13,10 +15,13 @@ import (
 //
 // https://sqlite.org/c3ref/stmt.html
 type Stmt struct {
-	c      *Conn
-	err    error
-	sql    string
-	handle ptr_t
+	c       *Conn
+	err     error
+	sql     string
+	budget  *budget
+	readers []bindReader
+	handle  ptr_t
+	strict  bool
 }
 
 // Close destroys the prepared statement object.
@@ -84,7 +89,19 @@ func (s *Stmt) ReadOnly() bool {
 // https://sqlite.org/c3ref/reset.html
 func (s *Stmt) Reset() error {
 	rc := res_t(s.c.call("sqlite3_reset", stk_t(s.handle)))
+	err := s.err
 	s.err = nil
+	if b := s.budget; b != nil {
+		berr := b.err
+		b.stop()
+		if berr != nil {
+			return berr
+		}
+	}
+	if rc == _OK && err != nil {
+		// An error writing a bound reader.
+		return err
+	}
 	return s.c.error(rc)
 }
 
@@ -106,6 +123,16 @@ func (s *Stmt) Busy() bool {
 //
 // https://sqlite.org/c3ref/step.html
 func (s *Stmt) Step() bool {
+	if s.readers != nil {
+		return s.stepReaders()
+	}
+	return s.step()
+}
+
+func (s *Stmt) step() bool {
+	if b := s.budget; b != nil {
+		return s.stepBudget(b)
+	}
 	s.c.checkInterrupt(s.c.handle)
 	rc := res_t(s.c.call("sqlite3_step", stk_t(s.handle)))
 	switch rc {
@@ -120,6 +147,38 @@ func (s *Stmt) Step() bool {
 	return false
 }
 
+func (s *Stmt) stepBudget(b *budget) bool {
+	if b.err != nil {
+		s.err = b.err
+		return false
+	}
+	b.start(s)
+
+	prev := s.c.budgeted
+	s.c.budgeted = s
+	s.c.checkInterrupt(s.c.handle)
+	rc := res_t(s.c.call("sqlite3_step", stk_t(s.handle)))
+	s.c.budgeted = prev
+
+	switch rc {
+	case _ROW:
+		s.err = nil
+		if b.row() {
+			return true
+		}
+		s.err = b.err
+	case _DONE:
+		s.err = nil
+		b.stop()
+	default:
+		s.err = s.c.error(rc)
+		if b.err != nil {
+			s.err = b.err
+		}
+	}
+	return false
+}
+
 // Err gets the last error occurred during [Stmt.Step].
 // Err returns nil after [Stmt.Reset] is called.
 //
@@ -156,6 +215,7 @@ func (s *Stmt) Status(op StmtStatus, reset bool) int {
 //
 // https://sqlite.org/c3ref/clear_bindings.html
 func (s *Stmt) ClearBindings() error {
+	s.readers = nil
 	rc := res_t(s.c.call("sqlite3_clear_bindings", stk_t(s.handle)))
 	return s.c.error(rc)
 }
@@ -376,6 +436,72 @@ func (s *Stmt) BindValue(param int, value Value) error {
 	return s.c.error(rc)
 }
 
+// bind binds a Go value to the prepared statement,
+// choosing the Bind method based on its type.
+func (s *Stmt) bind(param int, value any) error {
+	switch v := value.(type) {
+	case nil:
+		return s.BindNull(param)
+	case bool:
+		return s.BindBool(param, v)
+	case int:
+		return s.BindInt(param, v)
+	case int64:
+		return s.BindInt64(param, v)
+	case float64:
+		return s.BindFloat(param, v)
+	case string:
+		return s.BindText(param, v)
+	case []byte:
+		return s.BindBlob(param, v)
+	case time.Time:
+		return s.BindTime(param, v, TimeFormatDefault)
+	case ZeroBlob:
+		return s.BindZeroBlob(param, int64(v))
+	case Value:
+		return s.BindValue(param, v)
+	case util.JSON:
+		return s.BindJSON(param, v.Value)
+	case util.PointerUnwrap:
+		return s.BindPointer(param, util.UnwrapPointer(v))
+	case driver.Valuer:
+		v2, err := v.Value()
+		if err != nil {
+			return err
+		}
+		return s.bind(param, v2)
+	}
+
+	v := reflect.ValueOf(value)
+	k := v.Kind()
+
+	if k == reflect.Interface || k == reflect.Pointer {
+		if v.IsNil() {
+			return s.BindNull(param)
+		}
+		return s.bind(param, v.Elem().Interface())
+	}
+
+	switch {
+	case v.CanInt():
+		return s.BindInt64(param, v.Int())
+	case v.CanUint():
+		if u := v.Uint(); u <= math.MaxInt64 {
+			return s.BindInt64(param, int64(u))
+		}
+		return util.ValueErr
+	case v.CanFloat():
+		return s.BindFloat(param, v.Float())
+	case k == reflect.Bool:
+		return s.BindBool(param, v.Bool())
+	case k == reflect.String:
+		return s.BindText(param, v.String())
+	case k == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		return s.BindBlob(param, v.Bytes())
+	}
+	return util.ValueErr
+}
+
 // DataCount resets the number of columns in a result set.
 //
 // https://sqlite.org/c3ref/data_count.html
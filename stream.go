@@ -0,0 +1,378 @@
+package sqlite3
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ncruces/go-sqlite3/internal/util"
+	"github.com/ncruces/go-sqlite3/vfs"
+)
+
+// BackupTo backs up the schema database on the src connection,
+// writing it to w.
+//
+// The backup is copied in a single pass with [Backup.Run] and opts.
+// SQLite writes the first page of a backup last, when it commits,
+// so the backup is copied into a temporary file, then written to w.
+// The database is never held in memory.
+//
+// In WAL mode, the backup copies a snapshot of the database,
+// in a read transaction that doesn't block writers.
+// In rollback journal mode, the database is only locked during each step,
+// so that writers can commit between steps.
+// When they do, SQLite restarts the backup;
+// opts.Sleep gives the backup a chance to complete.
+//
+// BackupTo fails with [BUSY] if src has a write transaction,
+// and opts.Progress must not write to the database through src.
+//
+// https://sqlite.org/backup.html
+func (src *Conn) BackupTo(ctx context.Context, schema string, w io.Writer, opts *BackupOptions) (err error) {
+	if schema == "" {
+		schema = "main"
+	}
+
+	if src.TxnState(schema) == TXN_WRITE {
+		return BUSY
+	}
+	wal, err := src.isWAL(schema)
+	if err != nil {
+		return err
+	}
+	if wal && src.GetAutocommit() {
+		err := src.Exec(`BEGIN; PRAGMA ` + QuoteIdentifier(schema) + `.schema_version`)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if e := src.Exec(`COMMIT`); err == nil {
+				err = e
+			}
+		}()
+	}
+
+	file, err := newTempFile()
+	if err != nil {
+		return err
+	}
+	defer file.remove()
+
+	b, err := src.BackupInit(schema, file.uri())
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	if err := b.Run(ctx, opts); err != nil {
+		return err
+	}
+	if err := b.Close(); err != nil {
+		return err
+	}
+
+	size, err := file.Size()
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, io.NewSectionReader(file.f, 0, size))
+	return err
+}
+
+func (c *Conn) isWAL(schema string) (bool, error) {
+	stmt, _, err := c.Prepare(`PRAGMA ` + QuoteIdentifier(schema) + `.journal_mode`)
+	if err != nil {
+		return false, err
+	}
+	defer stmt.Close()
+
+	if !stmt.Step() {
+		return false, stmt.Err()
+	}
+	return stmt.ColumnText(0) == "wal", nil
+}
+
+// RestoreFrom restores the schema database on the dst connection
+// from a database read from r,
+// DESTROYING any contents previously stored in schema.
+//
+// The database is read from r as it's copied into schema
+// with [Backup.Run] and opts,
+// and only its first page is kept in memory.
+// If its header doesn't record its size,
+// it's read into a temporary file before the copy.
+//
+// https://sqlite.org/backup.html
+func (dst *Conn) RestoreFrom(ctx context.Context, schema string, r io.Reader, opts *BackupOptions) error {
+	if schema == "" {
+		schema = "main"
+	}
+
+	file, err := newReaderFile(r)
+	if err != nil {
+		return err
+	}
+	if f, ok := file.(*tempFile); ok {
+		defer f.remove()
+	}
+
+	b, err := dst.RestoreInit(schema, streamURI(file))
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	err = b.Run(ctx, opts)
+	if f, ok := file.(*readerFile); ok && f.err != nil {
+		return f.err
+	}
+	if err != nil {
+		return err
+	}
+	return b.Close()
+}
+
+// streamVFS opens the files used by
+// [Conn.BackupTo] and [Conn.RestoreFrom].
+// Each file is opened once, by the name streamURI registers it with.
+type streamVFS struct{}
+
+const streamVFSName = "github.com/ncruces/go-sqlite3.streamVFS"
+
+var (
+	streamFileID   atomic.Uint64
+	streamFiles    sync.Map // map[string]vfs.File
+	streamRegister sync.Once
+)
+
+func streamURI(f vfs.File) string {
+	streamRegister.Do(func() { vfs.Register(streamVFSName, streamVFS{}) })
+	name := "stream-" + strconv.FormatUint(streamFileID.Add(1), 10) + ".db"
+	streamFiles.Store(name, f)
+	return "file:" + name + "?vfs=" + streamVFSName
+}
+
+func (streamVFS) Open(name string, flags vfs.OpenFlag) (vfs.File, vfs.OpenFlag, error) {
+	if flags&vfs.OPEN_MAIN_DB == 0 {
+		return nil, flags, CANTOPEN
+	}
+	if f, ok := streamFiles.LoadAndDelete(name); ok {
+		return f.(vfs.File), flags | vfs.OPEN_MEMORY, nil
+	}
+	return nil, flags, CANTOPEN
+}
+
+func (streamVFS) Delete(name string, dirSync bool) error {
+	// notest // OPEN_MEMORY
+	return IOERR_DELETE
+}
+
+func (streamVFS) Access(name string, flag vfs.AccessFlag) (bool, error) {
+	_, ok := streamFiles.Load(name)
+	return ok, nil
+}
+
+func (streamVFS) FullPathname(name string) (string, error) {
+	return name, nil
+}
+
+// tempFile is a database in a temporary file,
+// which outlives the connections that open it.
+type tempFile struct {
+	nopFile
+	f *os.File
+}
+
+func newTempFile() (*tempFile, error) {
+	f, err := os.CreateTemp("", "sqlite3-*.db")
+	if err != nil {
+		return nil, err
+	}
+	return &tempFile{f: f}, nil
+}
+
+func (f *tempFile) uri() string {
+	return streamURI(f)
+}
+
+func (f *tempFile) remove() {
+	f.f.Close()
+	os.Remove(f.f.Name())
+}
+
+func (f *tempFile) ReadAt(b []byte, off int64) (n int, err error) {
+	return f.f.ReadAt(b, off)
+}
+
+func (f *tempFile) WriteAt(b []byte, off int64) (n int, err error) {
+	return f.f.WriteAt(b, off)
+}
+
+func (f *tempFile) Size() (int64, error) {
+	fi, err := f.f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return fi.Size(), nil
+}
+
+func (f *tempFile) Truncate(size int64) error {
+	return f.f.Truncate(size)
+}
+
+// readerFile reads a database from an io.Reader.
+// The first page is kept in memory,
+// and other pages must be read in order.
+type readerFile struct {
+	nopFile
+	r     io.Reader
+	err   error
+	first []byte
+	off   int64 // Bytes read from r.
+	size  int64
+}
+
+// newReaderFile reads the first page of a database from r.
+// If the database header doesn't record the size of the database,
+// it copies the entire database into a temporary file.
+//
+// https://sqlite.org/fileformat.html#the_database_header
+func newReaderFile(r io.Reader) (vfs.File, error) {
+	var hdr [100]byte
+	_, err := io.ReadFull(r, hdr[:])
+	if err == io.EOF {
+		f, err := newTempFile() // empty database
+		if err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
+	if err == io.ErrUnexpectedEOF || string(hdr[:16]) != "SQLite format 3\x00" {
+		return nil, NOTADB
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pageSize := int(binary.BigEndian.Uint16(hdr[16:]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 {
+		return nil, NOTADB
+	}
+	// Open databases in WAL mode in rollback journal mode.
+	if hdr[18] == 2 && hdr[19] == 2 {
+		hdr[18] = 1
+		hdr[19] = 1
+	}
+
+	first := make([]byte, pageSize)
+	copy(first, hdr[:])
+	if _, err := io.ReadFull(r, first[len(hdr):]); err != nil {
+		return nil, err
+	}
+
+	pages := binary.BigEndian.Uint32(hdr[28:])
+	if pages == 0 || !bytes.Equal(hdr[24:28], hdr[92:96]) {
+		f, err := newTempFile()
+		if err != nil {
+			return nil, err
+		}
+		_, err = io.Copy(f.f, io.MultiReader(bytes.NewReader(first), r))
+		if err != nil {
+			f.remove()
+			return nil, err
+		}
+		return f, nil
+	}
+
+	return &readerFile{
+		r:     r,
+		first: first,
+		off:   int64(pageSize),
+		size:  int64(pages) * int64(pageSize),
+	}, nil
+}
+
+func (f *readerFile) ReadAt(b []byte, off int64) (n int, err error) {
+	if off+int64(len(b)) <= int64(len(f.first)) {
+		return copy(b, f.first[off:]), nil
+	}
+	if off >= f.size {
+		return 0, io.EOF
+	}
+	if off < f.off {
+		return 0, f.fail(util.SeekErr)
+	}
+	if off > f.off {
+		m, err := io.CopyN(io.Discard, f.r, off-f.off)
+		f.off += m
+		if err != nil {
+			return 0, f.fail(err)
+		}
+	}
+	n, err = io.ReadFull(f.r, b)
+	f.off += int64(n)
+	if err != nil {
+		return n, f.fail(err)
+	}
+	return n, nil
+}
+
+func (f *readerFile) WriteAt(b []byte, off int64) (n int, err error) {
+	// notest // opened read-only
+	return 0, READONLY
+}
+
+func (f *readerFile) Size() (int64, error) {
+	return f.size, nil
+}
+
+func (f *readerFile) Truncate(size int64) error {
+	// notest // opened read-only
+	return READONLY
+}
+
+func (f *readerFile) fail(err error) error {
+	if f.err == nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		f.err = err
+	}
+	return err
+}
+
+// nopFile implements the methods of an in-memory [vfs.File]
+// that don't touch its contents.
+type nopFile struct{}
+
+func (nopFile) Close() error { return nil }
+
+func (nopFile) Sync(flag vfs.SyncFlag) error { return nil }
+
+func (nopFile) Lock(lock vfs.LockLevel) error { return nil }
+
+func (nopFile) Unlock(lock vfs.LockLevel) error { return nil }
+
+func (nopFile) CheckReservedLock() (bool, error) {
+	// notest // OPEN_MEMORY
+	return false, nil
+}
+
+func (nopFile) SectorSize() int {
+	// notest // IOCAP_POWERSAFE_OVERWRITE
+	return 0
+}
+
+func (nopFile) DeviceCharacteristics() vfs.DeviceCharacteristic {
+	return vfs.IOCAP_SEQUENTIAL |
+		vfs.IOCAP_POWERSAFE_OVERWRITE |
+		vfs.IOCAP_SUBPAGE_READ
+}
@@ -1,11 +1,18 @@
 package tests
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/ncruces/go-sqlite3"
 	_ "github.com/ncruces/go-sqlite3/embed"
+	"github.com/ncruces/go-sqlite3/ext/serdes"
 	_ "github.com/ncruces/go-sqlite3/internal/testcfg"
 	"github.com/ncruces/go-sqlite3/vfs"
 )
@@ -172,3 +179,284 @@ func TestBackup(t *testing.T) {
 		}
 	}()
 }
+
+func TestBackupTo(t *testing.T) {
+	t.Parallel()
+
+	open := func() *sqlite3.Conn {
+		t.Helper()
+		db, err := sqlite3.Open(":memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return db
+	}
+
+	count := func(db *sqlite3.Conn) int {
+		t.Helper()
+		stmt, _, err := db.Prepare(`SELECT count(*) FROM test`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer stmt.Close()
+		if !stmt.Step() {
+			t.Fatal(stmt.Err())
+		}
+		return stmt.ColumnInt(0)
+	}
+
+	db := open()
+	err := db.Exec(`
+		CREATE TABLE test (col);
+		INSERT INTO test SELECT randomblob(1000) FROM generate_series(1, 10000);
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := serdes.Serialize(db, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("large", func(t *testing.T) {
+		// Larger than the page cache of the destination.
+		var buf bytes.Buffer
+		err := db.BackupTo(context.Background(), "main", &buf, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), want) {
+			t.Fatal("backup differs")
+		}
+
+		restored := open()
+		r := bytes.NewReader(buf.Bytes())
+		err = restored.RestoreFrom(context.Background(), "", r, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := count(restored); got != 10000 {
+			t.Errorf("got %d rows, want 10000", got)
+		}
+		if r.Len() != 0 {
+			t.Errorf("got %d bytes unread", r.Len())
+		}
+	})
+
+	t.Run("steps", func(t *testing.T) {
+		var buf bytes.Buffer
+		var steps, remaining int
+		err := db.BackupTo(context.Background(), "", &buf, &sqlite3.BackupOptions{
+			Pages: 100,
+			Sleep: time.Microsecond,
+			Progress: func(r, count int) {
+				steps++
+				remaining = r
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if steps < 2 {
+			t.Errorf("got %d steps", steps)
+		}
+		if remaining != 0 {
+			t.Errorf("got %d remaining", remaining)
+		}
+		if !bytes.Equal(buf.Bytes(), want) {
+			t.Fatal("backup differs")
+		}
+
+		restored := open()
+		err = restored.RestoreFrom(context.Background(), "main", &buf, &sqlite3.BackupOptions{
+			Pages: 100,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := count(restored); got != 10000 {
+			t.Errorf("got %d rows, want 10000", got)
+		}
+	})
+
+	t.Run("no size", func(t *testing.T) {
+		// Clear the database size in the header.
+		data := bytes.Clone(want)
+		clear(data[28:32])
+
+		restored := open()
+		err := restored.RestoreFrom(context.Background(), "main", bytes.NewReader(data), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := count(restored); got != 10000 {
+			t.Errorf("got %d rows, want 10000", got)
+		}
+	})
+
+	t.Run("transaction", func(t *testing.T) {
+		err := db.Exec(`BEGIN`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Exec(`ROLLBACK`)
+
+		err = db.BackupTo(context.Background(), "main", io.Discard, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if db.GetAutocommit() {
+			t.Error("want transaction")
+		}
+
+		err = db.Exec(`DELETE FROM test WHERE rowid = 1`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = db.BackupTo(context.Background(), "main", io.Discard, nil)
+		if !errors.Is(err, sqlite3.BUSY) {
+			t.Errorf("got %v, want BUSY", err)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := open().BackupTo(context.Background(), "main", &buf, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		restored := open()
+		err = restored.Exec(`CREATE TABLE test (col)`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = restored.RestoreFrom(context.Background(), "main", &buf, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, err = restored.Prepare(`SELECT * FROM test`)
+		if err == nil {
+			t.Error("want error")
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := db.BackupTo(ctx, "main", io.Discard, nil)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("got %v, want context.Canceled", err)
+		}
+
+		werr := errors.New("write failed")
+		err = db.BackupTo(context.Background(), "main", errWriter{werr}, nil)
+		if !errors.Is(err, werr) {
+			t.Errorf("got %v, want %v", err, werr)
+		}
+
+		restored := open()
+		err = restored.RestoreFrom(context.Background(), "main", strings.NewReader("not a database"), nil)
+		if !errors.Is(err, sqlite3.NOTADB) {
+			t.Errorf("got %v, want NOTADB", err)
+		}
+
+		err = restored.RestoreFrom(context.Background(), "main", bytes.NewReader(want[:len(want)/2]), nil)
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("got %v, want io.ErrUnexpectedEOF", err)
+		}
+	})
+}
+
+func TestBackupTo_writers(t *testing.T) {
+	if !vfs.SupportsFileLocking {
+		t.Skip("skipping without locks")
+	}
+	t.Parallel()
+
+	for _, mode := range []string{"delete", "wal"} {
+		t.Run(mode, func(t *testing.T) {
+			t.Parallel()
+
+			name := "file:" + filepath.ToSlash(filepath.Join(t.TempDir(), "test.db")) +
+				"?_pragma=journal_mode(" + mode + ")"
+
+			db, err := sqlite3.Open(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			writer, err := sqlite3.Open(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer writer.Close()
+
+			err = db.Exec(`
+				CREATE TABLE test (col);
+				INSERT INTO test SELECT randomblob(1000) FROM generate_series(1, 10000);
+			`)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Writers aren't blocked between steps.
+			// Write repeatedly, after most pages were copied.
+			var writes int
+			var buf bytes.Buffer
+			err = db.BackupTo(context.Background(), "main", &buf, &sqlite3.BackupOptions{
+				Pages: 100,
+				Sleep: time.Microsecond,
+				Progress: func(remaining, count int) {
+					if writes < 5 && remaining > 0 && remaining < count/4 {
+						if err := writer.Exec(`INSERT INTO test VALUES (1)`); err != nil {
+							t.Error(err)
+						}
+						writes++
+					}
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if writes != 5 {
+				t.Errorf("got %d writes, want 5", writes)
+			}
+
+			restored, err := sqlite3.Open(":memory:")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer restored.Close()
+
+			err = restored.RestoreFrom(context.Background(), "main", &buf, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// A WAL backup copies a snapshot,
+			// a rollback journal backup restarts to copy the writes.
+			want := 10000
+			if mode != "wal" {
+				want += writes
+			}
+			stmt, _, err := restored.Prepare(`SELECT count(*) FROM test`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer stmt.Close()
+			if !stmt.Step() {
+				t.Fatal(stmt.Err())
+			}
+			if got := stmt.ColumnInt(0); got != want {
+				t.Errorf("got %d rows, want %d", got, want)
+			}
+		})
+	}
+}
+
+type errWriter struct{ err error }
+
+func (w errWriter) Write([]byte) (int, error) { return 0, w.err }
@@ -12,6 +12,7 @@ import (
 	"github.com/ncruces/go-sqlite3"
 	_ "github.com/ncruces/go-sqlite3/embed"
 	_ "github.com/ncruces/go-sqlite3/internal/testcfg"
+	"github.com/ncruces/go-sqlite3/internal/util"
 )
 
 func TestBlob(t *testing.T) {
@@ -423,3 +424,177 @@ func TestBlob_Reopen(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestStmt_BindReader(t *testing.T) {
+	t.Parallel()
+
+	db, err := sqlite3.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = db.Exec(`
+		CREATE TABLE test (a BLOB, b BLOB);
+		CREATE TABLE log (data BLOB);
+		CREATE TRIGGER test_log AFTER INSERT ON test BEGIN
+			INSERT INTO log VALUES (zeroblob(length(NEW.a)));
+		END;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const size = 1024 * 1024
+	var data [size]byte
+	_, err = rand.Read(data[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query, _, err := db.Prepare(`SELECT rowid FROM test WHERE rowid = ?`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer query.Close()
+
+	check := func(rowid int64, a, b []byte) {
+		t.Helper()
+		query.BindInt64(1, rowid)
+		if !query.Step() {
+			t.Fatal(query.Err())
+		}
+		for i, want := range [][]byte{a, b} {
+			column := [...]string{"a", "b"}[i]
+			blob, err := query.ColumnReader(0, "main", "test", column)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := io.ReadAll(blob)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := blob.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("row %d, column %s: data mismatch", rowid, column)
+			}
+		}
+		if err := query.Reset(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Parameters bound out of column order,
+	// both the same size as the BLOB inserted by the trigger.
+	stmt, _, err := db.Prepare(`INSERT INTO test (b, a) VALUES (?, ?) RETURNING rowid`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+
+	err = stmt.BindReader(1, bytes.NewReader(data[size/2:]), size/2, "main", "test", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = stmt.BindReader(2, bytes.NewReader(data[:size/2]), size/2, "main", "test", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stmt.Step() {
+		t.Fatal(stmt.Err())
+	}
+	rowid := stmt.ColumnInt64(0)
+	if err := stmt.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	check(rowid, data[:size/2], data[size/2:])
+
+	// The trigger row is left alone.
+	var zero [size / 2]byte
+	logs, _, err := db.Prepare(`SELECT data FROM log`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logs.Close()
+	if !logs.Step() {
+		t.Fatal(logs.Err())
+	}
+	if got := logs.ColumnRawBlob(0); !bytes.Equal(got, zero[:]) {
+		t.Error("trigger row was modified")
+	}
+	if err := logs.Reset(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Updating one column doesn't touch the other,
+	// even if both hold BLOBs of the same size.
+	update, _, err := db.Prepare(`UPDATE test SET b = ? WHERE rowid = ? RETURNING rowid`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer update.Close()
+
+	err = update.BindReader(1, bytes.NewReader(data[:size/2]), size/2, "main", "test", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	update.BindInt64(2, rowid)
+	err = update.Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(rowid, data[:size/2], data[:size/2])
+
+	// A short reader fails, and in a transaction can be rolled back.
+	err = db.Exec(`BEGIN`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = update.BindReader(1, bytes.NewReader(data[:10]), size, "main", "test", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	update.BindInt64(2, rowid)
+	err = update.Exec()
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("got %v, want io.ErrUnexpectedEOF", err)
+	}
+	err = db.Exec(`ROLLBACK`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(rowid, data[:size/2], data[:size/2])
+
+	// No row changed.
+	err = update.BindReader(1, bytes.NewReader(data[:]), size, "main", "test", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	update.BindInt64(2, rowid+1)
+	err = update.Exec()
+	if !errors.Is(err, util.NoBlobErr) {
+		t.Errorf("got %v, want util.NoBlobErr", err)
+	}
+
+	// Wrong column for the bound BLOB.
+	err = update.BindReader(1, bytes.NewReader(data[:]), size, "main", "test", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	update.BindInt64(2, rowid)
+	err = update.Exec()
+	if !errors.Is(err, util.NoBlobErr) {
+		t.Errorf("got %v, want util.NoBlobErr", err)
+	}
+
+	// The column isn't a rowid.
+	if !logs.Step() {
+		t.Fatal(logs.Err())
+	}
+	_, err = logs.ColumnReader(0, "main", "log", "data")
+	if !errors.Is(err, util.NoBlobErr) {
+		t.Errorf("got %v, want util.NoBlobErr", err)
+	}
+}
@@ -0,0 +1,96 @@
+package compile
+
+import (
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ncruces/go-sqlite3"
+	_ "github.com/ncruces/go-sqlite3/embed"
+)
+
+func TestCompile_cache(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping in short mode")
+	}
+
+	dir := t.TempDir()
+	t.Setenv("Test_Compile_cachedir", dir)
+
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	compile := func(modified bool) map[string]time.Time {
+		t.Helper()
+		cmd := exec.Command(exe, append(os.Args[1:], "-test.run=Test_ChildProcess_cache")...)
+		if modified {
+			cmd.Env = append(os.Environ(), "Test_Compile_modified=1")
+		}
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%v: %s", err, out)
+		}
+
+		files := map[string]time.Time{}
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			files[path] = info.ModTime()
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return files
+	}
+
+	first := compile(false)
+	if len(first) == 0 {
+		t.Skip("no compiler cache on this platform")
+	}
+
+	// The same binary hits the cache.
+	second := compile(false)
+	if len(second) != len(first) {
+		t.Errorf("got %d cached modules, want %d", len(second), len(first))
+	}
+	for path, mod := range first {
+		if !second[path].Equal(mod) {
+			t.Errorf("cached module %s was rewritten", path)
+		}
+	}
+
+	// A different binary misses it.
+	third := compile(true)
+	if len(third) != len(first)+1 {
+		t.Errorf("got %d cached modules, want %d", len(third), len(first)+1)
+	}
+}
+
+func Test_ChildProcess_cache(t *testing.T) {
+	dir := os.Getenv("Test_Compile_cachedir")
+	if dir == "" || testing.Short() {
+		t.SkipNow()
+	}
+
+	sqlite3.CacheDir = dir
+	if os.Getenv("Test_Compile_modified") != "" {
+		// Append a custom section named "test".
+		sqlite3.Binary = append(slices.Clip(sqlite3.Binary), 0, 6, 4, 't', 'e', 's', 't', 0)
+	}
+
+	db, err := sqlite3.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+}
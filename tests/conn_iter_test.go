@@ -0,0 +1,53 @@
+//go:build go1.23
+
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ncruces/go-sqlite3"
+	_ "github.com/ncruces/go-sqlite3/embed"
+	_ "github.com/ncruces/go-sqlite3/internal/testcfg"
+)
+
+func TestConn_Query(t *testing.T) {
+	t.Parallel()
+
+	db, err := sqlite3.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var sum int64
+	for stmt, err := range db.Query(`SELECT value FROM generate_series(?, ?)`, 1, int8(10)) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum += stmt.ColumnInt64(0)
+	}
+	if sum != 55 {
+		t.Errorf("got %d, want 55", sum)
+	}
+
+	// Breaking out early closes the statement.
+	for range db.Query(`SELECT value FROM generate_series(1, 10)`) {
+		break
+	}
+	for stmt := range db.Stmts() {
+		t.Errorf("statement not closed: %v", stmt)
+	}
+
+	for _, err := range db.Query(`SELECT * FROM missing`) {
+		if !errors.Is(err, sqlite3.ERROR) {
+			t.Errorf("got %v, want sqlite3.ERROR", err)
+		}
+	}
+
+	for _, err := range db.Query(`SELECT ?`, struct{}{}) {
+		if err == nil {
+			t.Error("want error")
+		}
+	}
+}
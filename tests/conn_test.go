@@ -179,6 +179,71 @@ func TestConn_SetInterrupt(t *testing.T) {
 	}
 }
 
+func TestConn_ProgressHandler(t *testing.T) {
+	t.Parallel()
+
+	db, err := sqlite3.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	const query = `
+		WITH RECURSIVE c(x) AS (VALUES(1) UNION ALL SELECT x+1 FROM c LIMIT 1e5)
+		SELECT sum(x) FROM c
+	`
+
+	var calls int
+	db.ProgressHandler(1000, func() bool {
+		calls++
+		return false
+	})
+
+	err = db.Exec(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls == 0 {
+		t.Error("progress handler not called")
+	}
+
+	db.ProgressHandler(1000, func() bool {
+		return true
+	})
+
+	// Interrupting works.
+	err = db.Exec(query)
+	if !errors.Is(err, sqlite3.INTERRUPT) {
+		t.Errorf("got %v, want sqlite3.INTERRUPT", err)
+	}
+
+	// Interrupting doesn't stick.
+	db.ProgressHandler(0, nil)
+	err = db.Exec(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	db.SetInterrupt(ctx)
+	cancel()
+
+	calls = 0
+	db.ProgressHandler(1000, func() bool {
+		calls++
+		return false
+	})
+
+	// Both work together.
+	err = db.Exec(query)
+	if !errors.Is(err, sqlite3.INTERRUPT) {
+		t.Errorf("got %v, want sqlite3.INTERRUPT", err)
+	}
+	if calls != 0 {
+		t.Errorf("got %d calls, want 0", calls)
+	}
+}
+
 func TestConn_Prepare_empty(t *testing.T) {
 	t.Parallel()
 
@@ -257,9 +322,28 @@ func TestConn_Prepare_invalid(t *testing.T) {
 	if got := serr.SQL(); got != `FRM sqlite_schema` {
 		t.Error("got SQL:", got)
 	}
+	if got := serr.Offset(); got != 9 {
+		t.Error("got offset:", got)
+	}
 	if got := serr.Error(); got != `sqlite3: SQL logic error: near "FRM": syntax error` {
 		t.Error("got message:", got)
 	}
+
+	_, _, err = db.Prepare(`SELEC 1`)
+	if !errors.As(err, &serr) {
+		t.Fatalf("got %T, want sqlite3.ERROR", err)
+	}
+	if got := serr.Offset(); got != 0 {
+		t.Error("got offset:", got)
+	}
+
+	err = db.Exec(`SELECT abs(-9223372036854775808)`)
+	if !errors.As(err, &serr) {
+		t.Fatalf("got %T, want sqlite3.ERROR", err)
+	}
+	if got := serr.Offset(); got != -1 {
+		t.Error("got offset:", got)
+	}
 }
 
 func TestConn_ReleaseMemory(t *testing.T) {
@@ -464,3 +548,78 @@ func TestConn_TableColumnMetadata(t *testing.T) {
 		t.Error("want false")
 	}
 }
+
+func TestError_Constraint(t *testing.T) {
+	t.Parallel()
+
+	db, err := sqlite3.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = db.Exec(`
+		PRAGMA foreign_keys = on;
+		CREATE TABLE users (
+			id    INTEGER PRIMARY KEY,
+			email TEXT NOT NULL UNIQUE,
+			age   INTEGER CONSTRAINT adult CHECK (age >= 18),
+			first TEXT, last TEXT,
+			UNIQUE (first, last)
+		) STRICT;
+		CREATE TABLE posts (user INTEGER REFERENCES users);
+		CREATE TABLE tags (name TEXT);
+		CREATE UNIQUE INDEX tags_lower ON tags (lower(name));
+		INSERT INTO users VALUES (1, 'a@example.com', 20, 'A', 'B');
+		INSERT INTO tags VALUES ('a');
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		sql  string
+		want sqlite3.Constraint
+	}{
+		{`INSERT INTO users VALUES (2, 'a@example.com', 20, NULL, NULL)`,
+			sqlite3.Constraint{Kind: sqlite3.CONSTRAINT_UNIQUE, Table: "users", Columns: []string{"email"}}},
+		{`INSERT INTO users VALUES (2, 'b@example.com', 20, 'A', 'B')`,
+			sqlite3.Constraint{Kind: sqlite3.CONSTRAINT_UNIQUE, Table: "users", Columns: []string{"first", "last"}}},
+		{`INSERT INTO tags VALUES ('A')`,
+			sqlite3.Constraint{Kind: sqlite3.CONSTRAINT_UNIQUE, Name: "tags_lower"}},
+		{`INSERT INTO users VALUES (1, 'b@example.com', 20, NULL, NULL)`,
+			sqlite3.Constraint{Kind: sqlite3.CONSTRAINT_PRIMARYKEY, Table: "users", Columns: []string{"id"}}},
+		{`INSERT INTO users VALUES (2, NULL, 20, NULL, NULL)`,
+			sqlite3.Constraint{Kind: sqlite3.CONSTRAINT_NOTNULL, Table: "users", Columns: []string{"email"}}},
+		{`INSERT INTO users VALUES (2, 'b@example.com', 10, NULL, NULL)`,
+			sqlite3.Constraint{Kind: sqlite3.CONSTRAINT_CHECK, Name: "adult"}},
+		{`INSERT INTO users VALUES (2, 'b@example.com', 'old', NULL, NULL)`,
+			sqlite3.Constraint{Kind: sqlite3.CONSTRAINT_DATATYPE, Table: "users", Columns: []string{"age"}}},
+		{`INSERT INTO posts VALUES (2)`,
+			sqlite3.Constraint{Kind: sqlite3.CONSTRAINT_FOREIGNKEY}},
+	}
+	for _, tt := range tests {
+		err := db.Exec(tt.sql)
+		var serr *sqlite3.Error
+		if !errors.As(err, &serr) {
+			t.Fatalf("got %v, want sqlite3.Error", err)
+		}
+		got := serr.Constraint()
+		if got == nil {
+			t.Fatalf("%s: got nil", tt.sql)
+		}
+		if got.Kind != tt.want.Kind || got.Table != tt.want.Table || got.Name != tt.want.Name ||
+			strings.Join(got.Columns, ",") != strings.Join(tt.want.Columns, ",") {
+			t.Errorf("%s: got %+v, want %+v", tt.sql, *got, tt.want)
+		}
+	}
+
+	err = db.Exec(`SELECT * FRM users`)
+	var serr *sqlite3.Error
+	if !errors.As(err, &serr) {
+		t.Fatalf("got %v, want sqlite3.Error", err)
+	}
+	if serr.Constraint() != nil {
+		t.Error("want nil")
+	}
+}
@@ -2,7 +2,9 @@ package tests
 
 import (
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/ncruces/go-sqlite3"
 	_ "github.com/ncruces/go-sqlite3/embed"
@@ -337,3 +339,214 @@ func TestPointer(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestFunc2(t *testing.T) {
+	t.Parallel()
+
+	db, err := sqlite3.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = sqlite3.Func2(db, "repeat", 0, func(s string, n int) (string, error) {
+		if n < 0 {
+			return "", errors.New("negative count")
+		}
+		return strings.Repeat(s, n), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = sqlite3.Func1(db, "coalesce_len", 0, func(s *string) (int, error) {
+		if s == nil {
+			return -1, nil
+		}
+		return len(*s), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = sqlite3.Func1(db, "json_keys", 0, func(m map[string]any) ([]string, error) {
+		var keys []string
+		for k := range m {
+			keys = append(keys, k)
+		}
+		return keys, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = sqlite3.Func1(db, "year", 0, func(t time.Time) (*int, error) {
+		y := t.Year()
+		return &y, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stmt, _, err := db.Prepare(`SELECT
+		repeat('ab', 3), repeat(NULL, 3), repeat('ab', NULL),
+		coalesce_len('abc'), coalesce_len(NULL),
+		json_keys('{"a":1}'), year('2024-05-06'), year(NULL)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+
+	if !stmt.Step() {
+		t.Fatal(stmt.Err())
+	}
+	if got := stmt.ColumnText(0); got != "ababab" {
+		t.Errorf("got %q", got)
+	}
+	if got := stmt.ColumnType(1); got != sqlite3.NULL {
+		t.Errorf("got %v, want NULL", got)
+	}
+	if got := stmt.ColumnType(2); got != sqlite3.NULL {
+		t.Errorf("got %v, want NULL", got)
+	}
+	if got := stmt.ColumnInt(3); got != 3 {
+		t.Errorf("got %d, want 3", got)
+	}
+	if got := stmt.ColumnInt(4); got != -1 {
+		t.Errorf("got %d, want -1", got)
+	}
+	if got := stmt.ColumnText(5); got != `["a"]` {
+		t.Errorf("got %q", got)
+	}
+	if got := stmt.ColumnInt(6); got != 2024 {
+		t.Errorf("got %d, want 2024", got)
+	}
+	if got := stmt.ColumnType(7); got != sqlite3.NULL {
+		t.Errorf("got %v, want NULL", got)
+	}
+	stmt.Close()
+
+	err = db.Exec(`SELECT repeat('ab', -1)`)
+	if err == nil || !strings.Contains(err.Error(), "negative count") {
+		t.Errorf("got %v", err)
+	}
+	err = sqlite3.Func1(db, "int8", 0, func(i int8) (int8, error) { return i, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Exec(`SELECT int8(1000)`)
+	if !errors.Is(err, sqlite3.MISMATCH) {
+		t.Errorf("got %v, want MISMATCH", err)
+	}
+}
+
+func TestFunc0_flags(t *testing.T) {
+	t.Parallel()
+
+	db, err := sqlite3.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var n int
+	err = sqlite3.Func0(db, "counter", 0, func() (int, error) {
+		n++
+		return n, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sqlite3.Func1(db, "twice", sqlite3.DETERMINISTIC|sqlite3.INNOCUOUS, func(i int) (int, error) {
+		return 2 * i, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Exec(`CREATE TABLE test (col)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Exec(`CREATE INDEX test_counter ON test (counter())`)
+	if err == nil {
+		t.Error("want error: counter is not deterministic")
+	}
+	err = db.Exec(`CREATE INDEX test_twice ON test (twice(col))`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stmt, _, err := db.Prepare(`SELECT counter(), counter()`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	if !stmt.Step() {
+		t.Fatal(stmt.Err())
+	}
+	if a, b := stmt.ColumnInt(0), stmt.ColumnInt(1); a == b {
+		t.Errorf("got %d, %d", a, b)
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	t.Parallel()
+
+	db, err := sqlite3.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = sqlite3.Aggregate[[]string, string, string]{
+		Step: func(state *[]string, s string) error {
+			*state = append(*state, s)
+			return nil
+		},
+		Inverse: func(state *[]string, s string) error {
+			*state = (*state)[1:]
+			return nil
+		},
+		Final: func(state *[]string) (string, error) {
+			return strings.Join(*state, "+"), nil
+		},
+	}.Register(db, "join_plus", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stmt, _, err := db.Prepare(`
+		SELECT join_plus(column1) FROM (VALUES ('a'), (NULL), ('b'), ('c'))`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+
+	if !stmt.Step() {
+		t.Fatal(stmt.Err())
+	}
+	if got := stmt.ColumnText(0); got != "a+b+c" {
+		t.Errorf("got %q", got)
+	}
+	stmt.Close()
+
+	stmt, _, err = db.Prepare(`
+		SELECT join_plus(value) OVER (ROWS 1 PRECEDING)
+		FROM (SELECT char(96+value) AS value FROM generate_series(1, 4))`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+
+	var got []string
+	for stmt.Step() {
+		got = append(got, stmt.ColumnText(0))
+	}
+	if err := stmt.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, " ") != "a a+b b+c c+d" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/ncruces/go-sqlite3"
+	_ "github.com/ncruces/go-sqlite3/embed"
+	_ "github.com/ncruces/go-sqlite3/internal/testcfg"
+)
+
+func TestQueryStructs(t *testing.T) {
+	t.Parallel()
+
+	db, err := sqlite3.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = db.Exec(`
+		CREATE TABLE users (id INTEGER PRIMARY KEY, name, email, tags, created, secret);
+		INSERT INTO users VALUES (1, 'alice', 'alice@example.com', '["a","b"]', 1700000000, 'x');
+		INSERT INTO users VALUES (2, 'bob', NULL, NULL, '2024-01-02 03:04:05', 'y');
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type Base struct {
+		ID int64 `sqlite:"id"`
+	}
+	type User struct {
+		Base
+		Name    string
+		Email   sql.NullString
+		Tags    []string  `sqlite:"tags,json"`
+		Created time.Time `sqlite:"created"`
+		Secret  string    `sqlite:"-"`
+		Pointer *string   `sqlite:"email"`
+	}
+
+	users, err := sqlite3.QueryStructs[User](db, `SELECT * FROM users WHERE id >= ? ORDER BY id`, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 {
+		t.Fatalf("got %d users, want 2", len(users))
+	}
+
+	alice, bob := users[0], users[1]
+	if alice.ID != 1 || alice.Name != "alice" || alice.Secret != "" {
+		t.Errorf("got %+v", alice)
+	}
+	if !alice.Email.Valid || alice.Email.String != "alice@example.com" {
+		t.Errorf("got %+v", alice.Email)
+	}
+	if len(alice.Tags) != 2 || alice.Tags[1] != "b" {
+		t.Errorf("got %v", alice.Tags)
+	}
+	if want := time.Unix(1700000000, 0); !alice.Created.Equal(want) {
+		t.Errorf("got %v, want %v", alice.Created, want)
+	}
+	if bob.Email.Valid || bob.Pointer != nil || bob.Tags != nil {
+		t.Errorf("got %+v", bob)
+	}
+	if want := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC); !bob.Created.Equal(want) {
+		t.Errorf("got %v, want %v", bob.Created, want)
+	}
+
+	_, err = sqlite3.QueryStructs[int](db, `SELECT 1`)
+	if err == nil {
+		t.Error("want error")
+	}
+
+	type Bad struct {
+		Name int8
+	}
+	_, err = sqlite3.QueryStructs[Bad](db, `SELECT 1000 AS name`)
+	if err == nil {
+		t.Error("want error")
+	}
+}
+
+func TestScanStruct(t *testing.T) {
+	t.Parallel()
+
+	db, err := sqlite3.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	stmt, _, err := db.Prepare(`SELECT 1 AS a, 2.5 AS b, x'cafe' AS c, 'text' AS d`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+
+	if !stmt.Step() {
+		t.Fatal(stmt.Err())
+	}
+
+	type Row struct {
+		A uint8
+		B float32
+		C []byte
+		D any
+	}
+	row, err := sqlite3.ScanStruct[Row](stmt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if row.A != 1 || row.B != 2.5 || string(row.C) != "\xca\xfe" || row.D != "text" {
+		t.Errorf("got %+v", row)
+	}
+}
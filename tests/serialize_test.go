@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ncruces/go-sqlite3"
+	_ "github.com/ncruces/go-sqlite3/embed"
+	_ "github.com/ncruces/go-sqlite3/internal/testcfg"
+)
+
+func TestConn_Serialize(t *testing.T) {
+	t.Parallel()
+
+	open := func() *sqlite3.Conn {
+		t.Helper()
+		db, err := sqlite3.Open(":memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return db
+	}
+
+	count := func(db *sqlite3.Conn) int {
+		t.Helper()
+		stmt, _, err := db.Prepare(`SELECT count(*) FROM test`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer stmt.Close()
+		if !stmt.Step() {
+			t.Fatal(stmt.Err())
+		}
+		return stmt.ColumnInt(0)
+	}
+
+	src := open()
+	err := src.Exec(`
+		CREATE TABLE test (col);
+		INSERT INTO test SELECT randomblob(1000) FROM generate_series(1, 10);
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := src.Serialize("", 0)
+	skipNoExport(t, err)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) == 0 || len(data)%4096 != 0 {
+		t.Fatalf("got %d bytes", len(data))
+	}
+
+	nocopy, err := src.Serialize("main", sqlite3.SERIALIZE_NOCOPY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nocopy != nil {
+		t.Error("want nil for a database not deserialized")
+	}
+
+	t.Run("roundtrip", func(t *testing.T) {
+		db := open()
+		err := db.Deserialize("", data, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := count(db); got != 10 {
+			t.Errorf("got %d rows, want 10", got)
+		}
+
+		copied, err := db.Serialize("main", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(copied, data) {
+			t.Error("serialized data differs")
+		}
+		nocopy, err := db.Serialize("main", sqlite3.SERIALIZE_NOCOPY)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(nocopy, data) {
+			t.Error("serialized data differs")
+		}
+
+		// Not resizeable: the database can't grow.
+		err = db.Exec(`INSERT INTO test SELECT randomblob(1000) FROM generate_series(1, 10)`)
+		if !errors.Is(err, sqlite3.FULL) {
+			t.Errorf("got %v, want FULL", err)
+		}
+		err = db.Exec(`DELETE FROM test WHERE rowid > 5`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := count(db); got != 5 {
+			t.Errorf("got %d rows, want 5", got)
+		}
+	})
+
+	t.Run("readonly", func(t *testing.T) {
+		db := open()
+		err := db.Deserialize("main", data, sqlite3.DESERIALIZE_READONLY)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := count(db); got != 10 {
+			t.Errorf("got %d rows, want 10", got)
+		}
+		err = db.Exec(`DELETE FROM test`)
+		if !errors.Is(err, sqlite3.READONLY) {
+			t.Errorf("got %v, want READONLY", err)
+		}
+	})
+
+	t.Run("resizeable", func(t *testing.T) {
+		db := open()
+		err := db.Deserialize("main", data, sqlite3.DESERIALIZE_RESIZEABLE)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		limit := len(data) + 64*1024
+		got, err := db.FileControl("main", sqlite3.FCNTL_SIZE_LIMIT, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != limit {
+			t.Errorf("got %v, want %d", got, limit)
+		}
+		got, err = db.FileControl("main", sqlite3.FCNTL_SIZE_LIMIT)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != limit {
+			t.Errorf("got %v, want %d", got, limit)
+		}
+
+		err = db.Exec(`INSERT INTO test SELECT randomblob(1000) FROM generate_series(1, 10)`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := count(db); got != 20 {
+			t.Errorf("got %d rows, want 20", got)
+		}
+		err = db.Exec(`INSERT INTO test SELECT randomblob(1000) FROM generate_series(1, 100)`)
+		if !errors.Is(err, sqlite3.FULL) {
+			t.Errorf("got %v, want FULL", err)
+		}
+	})
+}
@@ -0,0 +1,293 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ncruces/go-sqlite3"
+	_ "github.com/ncruces/go-sqlite3/embed"
+	_ "github.com/ncruces/go-sqlite3/internal/testcfg"
+	"github.com/ncruces/go-sqlite3/internal/util"
+)
+
+// skipNoExport skips tests of APIs
+// the embedded SQLite binary was built without.
+func skipNoExport(t testing.TB, err error) {
+	t.Helper()
+	if errors.Is(err, util.NoExportErr) {
+		t.Skip(err)
+	}
+}
+
+func TestSession(t *testing.T) {
+	t.Parallel()
+
+	open := func() *sqlite3.Conn {
+		t.Helper()
+		db, err := sqlite3.Open(":memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		err = db.Exec(`
+			CREATE TABLE test (id INTEGER PRIMARY KEY, val TEXT);
+			INSERT INTO test VALUES (1, 'a'), (2, 'b'), (3, 'c');
+		`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return db
+	}
+
+	rows := func(db *sqlite3.Conn) (res string) {
+		t.Helper()
+		stmt, _, err := db.Prepare(`SELECT group_concat(id || val, ',') FROM (SELECT * FROM test ORDER BY id)`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer stmt.Close()
+		if stmt.Step() {
+			res = stmt.ColumnText(0)
+		}
+		if err := stmt.Err(); err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	db := open()
+	session, err := db.CreateSession("")
+	skipNoExport(t, err)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+
+	err = session.Attach("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !session.IsEmpty() {
+		t.Error("want empty session")
+	}
+
+	err = db.Exec(`
+		UPDATE test SET val = 'B' WHERE id = 2;
+		DELETE FROM test WHERE id = 3;
+		INSERT INTO test VALUES (4, 'd');
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.IsEmpty() {
+		t.Error("want changes")
+	}
+
+	changeset, err := session.Changeset()
+	if err != nil {
+		t.Fatal(err)
+	}
+	patchset, err := session.Patchset()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patchset) == 0 || len(patchset) >= len(changeset) {
+		t.Errorf("got patchset of %d bytes, changeset of %d", len(patchset), len(changeset))
+	}
+
+	t.Run("iterate", func(t *testing.T) {
+		it, err := db.OpenChangeset(changeset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer it.Close()
+
+		ops := map[sqlite3.AuthorizerActionCode]string{}
+		for it.Next() {
+			table, columns, op, indirect, err := it.Operation()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if table != "test" || columns != 2 || indirect {
+				t.Errorf("got %q, %d, %v", table, columns, indirect)
+			}
+
+			pk, err := it.PrimaryKey()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(pk) != 2 || !pk[0] || pk[1] {
+				t.Errorf("got %v", pk)
+			}
+
+			var old, new sqlite3.Value
+			if op != sqlite3.AUTH_INSERT {
+				old, err = it.Old(1)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if op != sqlite3.AUTH_DELETE {
+				new, err = it.New(1)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			ops[op] = old.Text() + ">" + new.Text()
+		}
+		if err := it.Err(); err != nil {
+			t.Fatal(err)
+		}
+
+		want := map[sqlite3.AuthorizerActionCode]string{
+			sqlite3.AUTH_UPDATE: "b>B",
+			sqlite3.AUTH_DELETE: "c>",
+			sqlite3.AUTH_INSERT: ">d",
+		}
+		if len(ops) != len(want) {
+			t.Errorf("got %v", ops)
+		}
+		for op, w := range want {
+			if got := ops[op]; got != w {
+				t.Errorf("op %d: got %q, want %q", op, got, w)
+			}
+		}
+	})
+
+	t.Run("apply", func(t *testing.T) {
+		dst := open()
+		err := dst.ApplyChangeset(changeset, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := rows(dst); got != "1a,2B,4d" {
+			t.Errorf("got %q", got)
+		}
+
+		inverse, err := dst.InvertChangeset(changeset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = dst.ApplyChangeset(inverse, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := rows(dst); got != "1a,2b,3c" {
+			t.Errorf("got %q", got)
+		}
+	})
+
+	t.Run("patchset", func(t *testing.T) {
+		dst := open()
+		err := dst.ApplyChangeset(patchset, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := rows(dst); got != "1a,2B,4d" {
+			t.Errorf("got %q", got)
+		}
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		dst := open()
+		err := dst.ApplyChangeset(changeset, func(table string) bool {
+			return table != "test"
+		}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := rows(dst); got != "1a,2b,3c" {
+			t.Errorf("got %q", got)
+		}
+	})
+
+	t.Run("concat", func(t *testing.T) {
+		src := open()
+		session, err := src.CreateSession("main")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer session.Close()
+		err = session.Attach("test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = src.Exec(`INSERT INTO test VALUES (5, 'e')`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		other, err := session.Changeset()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		concat, err := src.ConcatChangesets(changeset, other)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dst := open()
+		err = dst.ApplyChangeset(concat, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := rows(dst); got != "1a,2B,4d,5e" {
+			t.Errorf("got %q", got)
+		}
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		dst := open()
+		err := dst.Exec(`
+			UPDATE test SET val = 'x' WHERE id = 2;
+			DELETE FROM test WHERE id = 3;
+			INSERT INTO test VALUES (4, 'z');
+		`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = dst.ApplyChangeset(changeset, nil, nil)
+		if !errors.Is(err, sqlite3.ABORT) {
+			t.Errorf("got %v, want ABORT", err)
+		}
+		if got := rows(dst); got != "1a,2x,4z" {
+			t.Errorf("got %q", got)
+		}
+
+		conflicts := map[sqlite3.ConflictType]int{}
+		err = dst.ApplyChangeset(changeset, nil, func(typ sqlite3.ConflictType, it *sqlite3.ChangesetIter) sqlite3.ConflictAction {
+			conflicts[typ]++
+			if typ == sqlite3.CHANGESET_DATA {
+				val, err := it.Conflict(1)
+				if err != nil {
+					t.Error(err)
+				}
+				if got := val.Text(); got != "x" {
+					t.Errorf("got %q", got)
+				}
+				return sqlite3.CHANGESET_REPLACE
+			}
+			return sqlite3.CHANGESET_OMIT
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := map[sqlite3.ConflictType]int{
+			sqlite3.CHANGESET_DATA:     1,
+			sqlite3.CHANGESET_NOTFOUND: 1,
+			sqlite3.CHANGESET_CONFLICT: 1,
+		}
+		if len(conflicts) != len(want) {
+			t.Errorf("got %v", conflicts)
+		}
+		for typ, n := range want {
+			if conflicts[typ] != n {
+				t.Errorf("conflict %d: got %d, want %d", typ, conflicts[typ], n)
+			}
+		}
+		if got := rows(dst); got != "1a,2B,4z" {
+			t.Errorf("got %q", got)
+		}
+	})
+}
@@ -2,8 +2,13 @@ package tests
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"math"
 	"math/bits"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -534,6 +539,36 @@ func TestStmt_Close(t *testing.T) {
 	stmt.Close()
 }
 
+func TestStmt_SQL(t *testing.T) {
+	t.Parallel()
+
+	db, err := sqlite3.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var sqls []string
+	tail := "SELECT 1; SELECT 2;\n-- comment\nSELECT 3 "
+	for {
+		stmt, rest, err := db.Prepare(tail)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stmt == nil {
+			break
+		}
+		sqls = append(sqls, stmt.SQL())
+		stmt.Close()
+		tail = rest
+	}
+
+	want := []string{"SELECT 1;", " SELECT 2;", "\n-- comment\nSELECT 3 "}
+	if !slices.Equal(sqls, want) {
+		t.Errorf("got %q, want %q", sqls, want)
+	}
+}
+
 func TestStmt_BindName(t *testing.T) {
 	t.Parallel()
 
@@ -722,3 +757,588 @@ func TestStmt_Error(t *testing.T) {
 		t.Log(err)
 	}
 }
+
+func TestStmt_SetBudget(t *testing.T) {
+	t.Parallel()
+
+	db, err := sqlite3.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	stmt, _, err := db.Prepare(`
+		WITH RECURSIVE c(x) AS (VALUES(1) UNION ALL SELECT x+1 FROM c LIMIT 1e5)
+		SELECT x FROM c
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+
+	stmt.SetBudget(sqlite3.Budget{Rows: 10})
+	var rows int
+	for stmt.Step() {
+		rows++
+	}
+	if rows != 10 {
+		t.Errorf("got %d rows, want 10", rows)
+	}
+	if err := stmt.Err(); !errors.Is(err, sqlite3.INTERRUPT_BUDGET) {
+		t.Errorf("got %v, want sqlite3.INTERRUPT_BUDGET", err)
+	}
+	if err := stmt.Reset(); !errors.Is(err, sqlite3.INTERRUPT_BUDGET) {
+		t.Errorf("got %v, want sqlite3.INTERRUPT_BUDGET", err)
+	}
+
+	// The budget is renewed after reset.
+	rows = 0
+	for stmt.Step() {
+		rows++
+	}
+	if rows != 10 {
+		t.Errorf("got %d rows, want 10", rows)
+	}
+	stmt.Reset()
+
+	stmt.SetBudget(sqlite3.Budget{Steps: 1000})
+	err = stmt.Exec()
+	if !errors.Is(err, sqlite3.INTERRUPT) {
+		t.Errorf("got %v, want sqlite3.INTERRUPT", err)
+	}
+	if !errors.Is(err, sqlite3.INTERRUPT_BUDGET) {
+		t.Errorf("got %v, want sqlite3.INTERRUPT_BUDGET", err)
+	}
+
+	// Without a budget, the statement runs to completion.
+	stmt.SetBudget(sqlite3.Budget{})
+	err = stmt.Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The connection remains usable.
+	err = db.Exec(`CREATE TABLE test (col)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stmt, _, err = db.Prepare(`
+		INSERT INTO test
+		WITH RECURSIVE c(x) AS (VALUES(1) UNION ALL SELECT x+1 FROM c LIMIT 1e4)
+		SELECT randomblob(1000) FROM c
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+
+	stmt.SetBudget(sqlite3.Budget{Memory: 1 << 20})
+	err = stmt.Exec()
+	if !errors.Is(err, sqlite3.INTERRUPT_BUDGET) {
+		t.Errorf("got %v, want sqlite3.INTERRUPT_BUDGET", err)
+	}
+
+	err = db.Exec(`SELECT count(*) FROM test`)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStmt_StepBatch(t *testing.T) {
+	t.Parallel()
+
+	db, err := sqlite3.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	stmt, _, err := db.Prepare(`
+		WITH RECURSIVE c(x) AS (VALUES(1) UNION ALL SELECT x+1 FROM c LIMIT 250)
+		SELECT x, x/2.0, 'row ' || x, CAST(x AS BLOB), NULL, x'' FROM c
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+
+	var batch sqlite3.Batch
+	var rows int
+	for more := true; more; {
+		more = stmt.StepBatch(100, &batch)
+		if n := batch.Len(); n > 100 {
+			t.Errorf("got %d rows in a batch", n)
+		}
+		for i := range batch.Len() {
+			rows++
+			row := batch.Row(i)
+			if got := row[0]; got != int64(rows) {
+				t.Errorf("got %v, want %d", got, rows)
+			}
+			if got := row[1]; got != float64(rows)/2 {
+				t.Errorf("got %v, want %v", got, float64(rows)/2)
+			}
+			if got := row[2]; got != "row "+strconv.Itoa(rows) {
+				t.Errorf("got %v, want %q", got, "row "+strconv.Itoa(rows))
+			}
+			if got := row[3].([]byte); string(got) != strconv.Itoa(rows) {
+				t.Errorf("got %q, want %q", got, strconv.Itoa(rows))
+			}
+			if got := row[4]; got != nil {
+				t.Errorf("got %v, want nil", got)
+			}
+			if got := row[5].([]byte); got == nil || len(got) != 0 {
+				t.Errorf("got %#v, want []byte{}", got)
+			}
+		}
+	}
+	if err := stmt.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if rows != 250 {
+		t.Errorf("got %d rows, want 250", rows)
+	}
+
+	stmt.Reset()
+	stmt.StepBatch(1, &batch)
+	if err := batch.Columns(0, nil); err == nil {
+		t.Error("want error")
+	}
+	row := make([]any, 6)
+	if err := batch.Columns(0, row...); err != nil {
+		t.Fatal(err)
+	}
+	if row[0] != int64(1) {
+		t.Errorf("got %v, want 1", row[0])
+	}
+
+	// Budgets are enforced.
+	stmt.Reset()
+	stmt.SetBudget(sqlite3.Budget{Rows: 10})
+	rows = 0
+	for more := true; more; {
+		more = stmt.StepBatch(100, &batch)
+		rows += batch.Len()
+	}
+	if rows != 10 {
+		t.Errorf("got %d rows, want 10", rows)
+	}
+	if err := stmt.Err(); !errors.Is(err, sqlite3.INTERRUPT_BUDGET) {
+		t.Errorf("got %v, want sqlite3.INTERRUPT_BUDGET", err)
+	}
+}
+
+func TestStmt_ExecBatch(t *testing.T) {
+	t.Parallel()
+
+	db, err := sqlite3.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = db.Exec(`CREATE TABLE test (id INTEGER PRIMARY KEY, name TEXT, score REAL, data BLOB)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stmt, _, err := db.Prepare(`INSERT INTO test VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+
+	err = stmt.ExecBatch([][]any{
+		{1, "one", 1.5, []byte("1")},
+		{2, "two", nil, sqlite3.ZeroBlob(4)},
+		{int8(3), time.Unix(0, 0).UTC(), float32(3.5), nil},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = stmt.ExecColumns(
+		[]int64{4, 5, 6},
+		[]string{"four", "five", "six"},
+		[]float64{4.5, 5.5, 6.5},
+		[]any{nil, []byte("5"), "6"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rows before the failing row are inserted.
+	err = stmt.ExecBatch([][]any{
+		{7, "seven", nil, nil},
+		{1, "duplicate", nil, nil},
+		{8, "eight", nil, nil},
+	})
+	var rowErr *sqlite3.RowError
+	if !errors.As(err, &rowErr) {
+		t.Fatalf("got %v, want sqlite3.RowError", err)
+	}
+	if rowErr.Row != 1 {
+		t.Errorf("got row %d, want 1", rowErr.Row)
+	}
+	if !errors.Is(err, sqlite3.CONSTRAINT_PRIMARYKEY) {
+		t.Errorf("got %v, want sqlite3.CONSTRAINT_PRIMARYKEY", err)
+	}
+
+	if err := stmt.ExecBatch([][]any{{9, "nine"}}); err == nil {
+		t.Error("want error")
+	}
+	if err := stmt.ExecColumns([]int{9}, []string{"nine"}, []float64{}, []any{nil}); err == nil {
+		t.Error("want error")
+	}
+
+	// Bindings are cleared.
+	if err := stmt.Exec(); err != nil {
+		t.Fatal(err)
+	}
+	err = db.Exec(`DELETE FROM test WHERE name IS NULL`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := db.Changes(); got != 1 {
+		t.Errorf("got %d, want 1", got)
+	}
+
+	var rows []string
+	sel, _, err := db.Prepare(`SELECT id, name, typeof(score), quote(data) FROM test ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sel.Close()
+	for sel.Step() {
+		rows = append(rows, fmt.Sprintf("%d %s %s %s",
+			sel.ColumnInt(0), sel.ColumnText(1), sel.ColumnText(2), sel.ColumnText(3)))
+	}
+	if err := sel.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"1 one real X'31'",
+		"2 two null X'00000000'",
+		"3 1970-01-01T00:00:00Z real NULL",
+		"4 four real NULL",
+		"5 five real X'35'",
+		"6 six real '6'",
+		"7 seven null NULL",
+	}
+	if !slices.Equal(rows, want) {
+		t.Errorf("got %q, want %q", rows, want)
+	}
+}
+
+func TestStmt_BindNamed(t *testing.T) {
+	t.Parallel()
+
+	db, err := sqlite3.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	stmt, _, err := db.Prepare(`SELECT :a, @b, $c, :d`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+
+	err = stmt.BindNamed(map[string]any{
+		"a":  1,
+		"@b": "two",
+		"c":  sqlite3.JSON([]int{3}),
+		"d":  sqlite3.ZeroBlob(4),
+		"e":  nil,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stmt.Step() {
+		t.Fatal(stmt.Err())
+	}
+	if got := stmt.ColumnInt(0); got != 1 {
+		t.Errorf("got %d, want 1", got)
+	}
+	if got := stmt.ColumnText(1); got != "two" {
+		t.Errorf("got %q, want two", got)
+	}
+	if got := stmt.ColumnText(2); got != "[3]" {
+		t.Errorf("got %q, want [3]", got)
+	}
+	if got := stmt.ColumnBlob(3, nil); len(got) != 4 {
+		t.Errorf("got %v, want 4 bytes", got)
+	}
+	stmt.Reset()
+
+	stmt.StrictBinding(true)
+	err = stmt.BindNamed(map[string]any{"a": 1, "b": 2, "c": 3, "d": 4, "e": 5})
+	if err == nil {
+		t.Error("want error for extra argument")
+	}
+	err = stmt.BindNamed(map[string]any{"a": 1, "b": 2, "c": 3})
+	if err == nil {
+		t.Error("want error for missing argument")
+	}
+	err = stmt.BindNamed(map[string]any{"a": 1, "b": 2, "c": 3, "d": 4})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStmt_BindStruct(t *testing.T) {
+	t.Parallel()
+
+	db, err := sqlite3.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	stmt, _, err := db.Prepare(`SELECT :id, :name, :tags, :created, :email, typeof(:email)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+
+	type Base struct {
+		ID int64 `sqlite:"id"`
+	}
+	type User struct {
+		*Base
+		Name    string
+		Tags    []string  `sqlite:"tags,json"`
+		Created time.Time `sqlite:"created,time=unixepoch"`
+		Email   *string
+		Secret  string `sqlite:"-"`
+	}
+
+	err = stmt.BindStruct(&User{
+		Base:    &Base{ID: 42},
+		Name:    "alice",
+		Tags:    []string{"a"},
+		Created: time.Unix(1700000000, 0),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stmt.Step() {
+		t.Fatal(stmt.Err())
+	}
+	if got := stmt.ColumnInt(0); got != 42 {
+		t.Errorf("got %d, want 42", got)
+	}
+	if got := stmt.ColumnText(1); got != "alice" {
+		t.Errorf("got %q, want alice", got)
+	}
+	if got := stmt.ColumnText(2); got != `["a"]` {
+		t.Errorf("got %q, want [\"a\"]", got)
+	}
+	if got := stmt.ColumnInt64(3); got != 1700000000 {
+		t.Errorf("got %d, want 1700000000", got)
+	}
+	if got := stmt.ColumnText(5); got != "null" {
+		t.Errorf("got %q, want null", got)
+	}
+	stmt.Reset()
+
+	// A nil embedded pointer binds NULL.
+	err = stmt.BindStruct(User{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stmt.StrictBinding(true)
+	err = stmt.BindStruct(struct{ ID, Name, Tags, Created, Email, Extra int }{})
+	if err == nil {
+		t.Error("want error for extra field")
+	}
+	err = stmt.BindStruct(struct{ ID, Name int }{})
+	if err == nil {
+		t.Error("want error for missing field")
+	}
+
+	err = stmt.BindStruct(42)
+	if err == nil {
+		t.Error("want error for non struct")
+	}
+}
+
+func TestStmt_Explain(t *testing.T) {
+	t.Parallel()
+
+	db, err := sqlite3.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = db.Exec(`
+		CREATE TABLE a (id INTEGER PRIMARY KEY, x);
+		CREATE TABLE b (id INTEGER PRIMARY KEY, a_id, y);
+		CREATE INDEX b_a ON b (a_id);
+		INSERT INTO a SELECT value, value FROM generate_series(1, 10);
+		INSERT INTO b SELECT NULL, value / 3, value FROM generate_series(3, 32);
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stmt, _, err := db.Prepare(`
+		SELECT a.x, b.y FROM a JOIN b ON b.a_id = a.id
+		WHERE a.id <= 5 AND b.y IN (SELECT value FROM generate_series(1, 100))`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+
+	plan, err := stmt.Explain(sqlite3.EXPLAIN_QUERY_PLAN)
+	skipNoExport(t, err)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var details []string
+	var nested bool
+	var walk func([]*sqlite3.QueryPlan)
+	walk = func(nodes []*sqlite3.QueryPlan) {
+		for _, n := range nodes {
+			details = append(details, n.Detail)
+			if len(n.Children) > 0 {
+				nested = true
+				walk(n.Children)
+			}
+		}
+	}
+	walk(plan)
+	if len(plan) < 2 || !nested {
+		t.Errorf("got %q", details)
+	}
+	if !strings.HasPrefix(plan[0].Detail, "SEARCH a ") {
+		t.Errorf("got %q", plan[0].Detail)
+	}
+	if !slices.ContainsFunc(details, func(s string) bool {
+		return strings.HasPrefix(s, "SEARCH b USING INDEX b_a ")
+	}) {
+		t.Errorf("got %q", details)
+	}
+
+	// Explain keeps the setting, and doesn't run the statement.
+	if mode, err := stmt.IsExplain(); err != nil || mode != sqlite3.EXPLAIN_QUERY_PLAN {
+		t.Errorf("got %v, %v", mode, err)
+	}
+	_, err = stmt.Explain(sqlite3.EXPLAIN_NONE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows int
+	for stmt.Step() {
+		rows++
+	}
+	if err := stmt.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if rows != 15 {
+		t.Errorf("got %d rows, want 15", rows)
+	}
+	err = stmt.Reset()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err = stmt.Explain(sqlite3.EXPLAIN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan != nil {
+		t.Errorf("got %v, want nil", plan)
+	}
+	if mode, err := stmt.IsExplain(); err != nil || mode != sqlite3.EXPLAIN {
+		t.Errorf("got %v, %v", mode, err)
+	}
+	if name := stmt.ColumnName(1); name != "opcode" {
+		t.Errorf("got %q", name)
+	}
+}
+
+func TestStmt_ScanStatus(t *testing.T) {
+	t.Parallel()
+
+	db, err := sqlite3.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = db.Exec(`
+		CREATE TABLE a (id INTEGER PRIMARY KEY, x);
+		CREATE TABLE b (id INTEGER PRIMARY KEY, a_id, y);
+		CREATE INDEX b_a ON b (a_id);
+		INSERT INTO a SELECT value, value FROM generate_series(1, 10);
+		INSERT INTO b SELECT NULL, value / 3, value FROM generate_series(3, 32);
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stmt, _, err := db.Prepare(`SELECT a.x, b.y FROM a JOIN b ON b.a_id = a.id WHERE a.id <= 5`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+
+	var rows int
+	for stmt.Step() {
+		rows++
+	}
+	if err := stmt.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if rows != 15 {
+		t.Errorf("got %d rows, want 15", rows)
+	}
+
+	scan := func() map[string]*sqlite3.ScanStatus {
+		t.Helper()
+		status, err := stmt.ScanStatus()
+		skipNoExport(t, err)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		loops := map[string]*sqlite3.ScanStatus{}
+		var walk func([]*sqlite3.ScanStatus)
+		walk = func(nodes []*sqlite3.ScanStatus) {
+			for _, n := range nodes {
+				if n.Name != "" {
+					loops[n.Name] = n
+				}
+				walk(n.Children)
+			}
+		}
+		walk(status)
+		return loops
+	}
+
+	loops := scan()
+	if a := loops["a"]; a == nil || a.Loops != 1 || a.Rows != 5 {
+		t.Errorf("got %+v", a)
+	}
+	if b := loops["b_a"]; b == nil || b.Loops != 5 || b.Rows != 15 {
+		t.Errorf("got %+v", b)
+	}
+	if a := loops["a"]; a != nil && !strings.HasPrefix(a.Explain, "SEARCH a ") {
+		t.Errorf("got %q", a.Explain)
+	}
+
+	err = stmt.ScanStatusReset()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loops = scan()
+	if a := loops["a"]; a == nil || a.Loops != 0 || a.Rows != 0 {
+		t.Errorf("got %+v", a)
+	}
+}
@@ -3,6 +3,8 @@ package tests
 import (
 	"context"
 	"errors"
+	"fmt"
+	"slices"
 	"testing"
 
 	"github.com/ncruces/go-sqlite3"
@@ -609,3 +611,95 @@ func TestConn_Savepoint_rollback(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestConn_PreUpdateHook(t *testing.T) {
+	t.Parallel()
+
+	db, err := sqlite3.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = db.Exec(`
+		CREATE TABLE test (id INTEGER PRIMARY KEY, val TEXT);
+		CREATE TABLE log (val TEXT);
+		CREATE TRIGGER trig AFTER UPDATE ON test BEGIN
+			INSERT INTO log VALUES (new.val);
+		END;
+		INSERT INTO test VALUES (1, 'a'), (2, 'b');
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	err = db.PreUpdateHook(func(ctx sqlite3.PreUpdate, action sqlite3.AuthorizerActionCode, schema, table string, oldRowID, newRowID int64) {
+		if schema != "main" {
+			t.Errorf("got %q", schema)
+		}
+		if n := ctx.BlobWrite(); n != -1 {
+			t.Errorf("got %d", n)
+		}
+
+		// The rowids of rows not in the table are undefined.
+		var old, new string
+		switch action {
+		case sqlite3.AUTH_INSERT:
+			oldRowID = 0
+		case sqlite3.AUTH_DELETE:
+			newRowID = 0
+		}
+		if action != sqlite3.AUTH_INSERT {
+			v, err := ctx.Old(ctx.Count() - 1)
+			if err != nil {
+				t.Error(err)
+			}
+			old = v.Text()
+		}
+		if action != sqlite3.AUTH_DELETE {
+			v, err := ctx.New(ctx.Count() - 1)
+			if err != nil {
+				t.Error(err)
+			}
+			new = v.Text()
+		}
+		got = append(got, fmt.Sprintf("%d %s %d>%d %s>%s %d",
+			action, table, oldRowID, newRowID, old, new, ctx.Depth()))
+	})
+	skipNoExport(t, err)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Exec(`UPDATE test SET val = 'B' WHERE id = 2`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Exec(`DELETE FROM test WHERE id = 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		fmt.Sprintf("%d test 2>2 b>B 0", sqlite3.AUTH_UPDATE),
+		fmt.Sprintf("%d log 0>1 >B 1", sqlite3.AUTH_INSERT),
+		fmt.Sprintf("%d test 1>0 a> 0", sqlite3.AUTH_DELETE),
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	err = db.PreUpdateHook(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = nil
+	err = db.Exec(`DELETE FROM test`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != nil {
+		t.Errorf("got %q", got)
+	}
+}
@@ -138,3 +138,117 @@ func TestConn_WALCheckpoint(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestWAL_snapshot(t *testing.T) {
+	if !vfs.SupportsSharedMemory {
+		t.Skip("skipping without shared memory")
+	}
+	t.Parallel()
+
+	file := filepath.Join(t.TempDir(), "test.db")
+
+	db1, err := sqlite3.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db1.Close()
+
+	err = db1.Exec(`
+		PRAGMA journal_mode=wal;
+		CREATE TABLE test (col);
+		INSERT INTO test VALUES (1);
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot := func() *sqlite3.Snapshot {
+		t.Helper()
+		err := db1.Exec(`BEGIN`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db1.Exec(`COMMIT`)
+
+		snap, err := db1.GetSnapshot("")
+		skipNoExport(t, err)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return snap
+	}
+
+	old := snapshot()
+	err = db1.Exec(`INSERT INTO test VALUES (2)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cur := snapshot()
+
+	compare := func(s1, s2 *sqlite3.Snapshot) int {
+		t.Helper()
+		res, err := db1.CompareSnapshots(s1, s2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	if got := compare(old, cur); got >= 0 {
+		t.Errorf("got %d, want < 0", got)
+	}
+	if got := compare(cur, old); got <= 0 {
+		t.Errorf("got %d, want > 0", got)
+	}
+	if got := compare(cur, snapshot()); got != 0 {
+		t.Errorf("got %d, want 0", got)
+	}
+
+	db2, err := sqlite3.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db2.Close()
+
+	count := func() int {
+		t.Helper()
+		stmt, _, err := db2.Prepare(`SELECT count(*) FROM test`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer stmt.Close()
+		if !stmt.Step() {
+			t.Fatal(stmt.Err())
+		}
+		return stmt.ColumnInt(0)
+	}
+
+	err = db2.RecoverSnapshot("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db2.OpenSnapshot("main", old)
+	if err == nil {
+		t.Error("want error in autocommit mode")
+	}
+
+	err = db2.Exec(`BEGIN`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db2.OpenSnapshot("main", old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := count(); got != 1 {
+		t.Errorf("got %d rows, want 1", got)
+	}
+	err = db2.Exec(`COMMIT`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := count(); got != 2 {
+		t.Errorf("got %d rows, want 2", got)
+	}
+}
@@ -277,6 +277,92 @@ func (c *Conn) UpdateHook(cb func(action AuthorizerActionCode, schema, table str
 	c.update = cb
 }
 
+// PreUpdateHook registers a callback function to be invoked
+// prior to each INSERT, UPDATE and DELETE operation on a database table.
+//
+// For an [AUTH_INSERT] oldRowID is undefined,
+// and for an [AUTH_DELETE] newRowID is undefined.
+//
+// https://sqlite.org/c3ref/preupdate_blobwrite.html
+func (c *Conn) PreUpdateHook(cb func(ctx PreUpdate, action AuthorizerActionCode, schema, table string, oldRowID, newRowID int64)) error {
+	if err := c.needfn("sqlite3_preupdate_hook_go"); err != nil {
+		return err
+	}
+	var enable int32
+	if cb != nil {
+		enable = 1
+	}
+	c.call("sqlite3_preupdate_hook_go", stk_t(c.handle), stk_t(enable))
+	c.preupdate = cb
+	return nil
+}
+
+// PreUpdate is the context in which a [Conn.PreUpdateHook] callback executes.
+// It is only valid for the duration of the callback.
+//
+// https://sqlite.org/c3ref/preupdate_blobwrite.html
+type PreUpdate struct {
+	c *Conn
+}
+
+// Old returns the value of a column of the table row before it is updated.
+// Old is only valid for [AUTH_UPDATE] and [AUTH_DELETE] operations.
+// The leftmost column has the index 0.
+//
+// https://sqlite.org/c3ref/preupdate_blobwrite.html
+func (ctx PreUpdate) Old(col int) (Value, error) {
+	return ctx.value("sqlite3_preupdate_old", col)
+}
+
+// New returns the value of a column of the table row after it is updated.
+// New is only valid for [AUTH_UPDATE] and [AUTH_INSERT] operations.
+// The leftmost column has the index 0.
+//
+// https://sqlite.org/c3ref/preupdate_blobwrite.html
+func (ctx PreUpdate) New(col int) (Value, error) {
+	return ctx.value("sqlite3_preupdate_new", col)
+}
+
+func (ctx PreUpdate) value(name string, col int) (Value, error) {
+	defer ctx.c.arena.mark()()
+	valPtr := ctx.c.arena.new(ptrlen)
+	rc := res_t(ctx.c.call(name, stk_t(ctx.c.handle), stk_t(col), stk_t(valPtr)))
+	if err := ctx.c.error(rc); err != nil {
+		return Value{}, err
+	}
+	return Value{
+		c:      ctx.c,
+		handle: util.Read32[ptr_t](ctx.c.mod, valPtr),
+	}, nil
+}
+
+// Count returns the number of columns in the row that is being modified.
+//
+// https://sqlite.org/c3ref/preupdate_blobwrite.html
+func (ctx PreUpdate) Count() int {
+	n := int32(ctx.c.call("sqlite3_preupdate_count", stk_t(ctx.c.handle)))
+	return int(n)
+}
+
+// Depth returns 0 if the change was caused by a direct INSERT, UPDATE or DELETE,
+// 1 for changes caused by a top-level trigger, 2 for changes caused
+// by triggers fired by top-level triggers, and so on.
+//
+// https://sqlite.org/c3ref/preupdate_blobwrite.html
+func (ctx PreUpdate) Depth() int {
+	n := int32(ctx.c.call("sqlite3_preupdate_depth", stk_t(ctx.c.handle)))
+	return int(n)
+}
+
+// BlobWrite returns the index of the column being written
+// if the change is caused by [Blob.Write], or -1 otherwise.
+//
+// https://sqlite.org/c3ref/preupdate_blobwrite.html
+func (ctx PreUpdate) BlobWrite() int {
+	n := int32(ctx.c.call("sqlite3_preupdate_blobwrite", stk_t(ctx.c.handle)))
+	return int(n)
+}
+
 func commitCallback(ctx context.Context, mod api.Module, pDB ptr_t) (rollback int32) {
 	if c, ok := ctx.Value(connKey{}).(*Conn); ok && c.handle == pDB && c.commit != nil {
 		if !c.commit() {
@@ -300,6 +386,14 @@ func updateCallback(ctx context.Context, mod api.Module, pDB ptr_t, action Autho
 	}
 }
 
+func preUpdateCallback(ctx context.Context, mod api.Module, _, pDB ptr_t, action AuthorizerActionCode, zSchema, zTabName ptr_t, iKey1, iKey2 int64) {
+	if c, ok := ctx.Value(connKey{}).(*Conn); ok && c.handle == pDB && c.preupdate != nil {
+		schema := util.ReadString(mod, zSchema, _MAX_NAME)
+		table := util.ReadString(mod, zTabName, _MAX_NAME)
+		c.preupdate(PreUpdate{c}, action, schema, table, iKey1, iKey2)
+	}
+}
+
 // CacheFlush flushes caches to disk mid-transaction.
 //
 // https://sqlite.org/c3ref/db_cacheflush.html
@@ -0,0 +1,217 @@
+// Package authz implements composable authorizer policies,
+// to run untrusted SQL in a sandbox.
+//
+// A policy is an ordered list of rules.
+// The first rule that matches an action decides whether it is
+// allowed, denied, or ignored; actions no rule matches are allowed:
+//
+//	policy := authz.Policy{
+//		authz.ReadOnly(),
+//		authz.DenyAttach(),
+//		authz.AllowPragmas("table_info"),
+//		authz.MaskColumns("users", "password"),
+//		authz.AllowTables("users", "posts"),
+//	}
+//	err := db.SetAuthorizer(policy.Authorizer(nil))
+//
+// https://sqlite.org/c3ref/set_authorizer.html
+package authz
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ncruces/go-sqlite3"
+)
+
+// Action is an action to be authorized.
+// The meaning of the names depends on the code.
+//
+// https://sqlite.org/c3ref/c_alter_table.html
+type Action struct {
+	Code    sqlite3.AuthorizerActionCode
+	Name3rd string
+	Name4th string
+	Schema  string
+	Inner   string // The innermost trigger or view responsible for the action.
+}
+
+// Rule decides the actions it matches.
+type Rule struct {
+	Name   string
+	Match  func(Action) bool
+	Result sqlite3.AuthorizerReturnCode
+}
+
+// Allow returns a rule that allows the actions match matches.
+func Allow(name string, match func(Action) bool) Rule {
+	return Rule{name, match, sqlite3.AUTH_OK}
+}
+
+// Deny returns a rule that denies the actions match matches,
+// failing the statement with a "not authorized" error.
+func Deny(name string, match func(Action) bool) Rule {
+	return Rule{name, match, sqlite3.AUTH_DENY}
+}
+
+// Ignore returns a rule that ignores the actions match matches.
+// Ignored column reads return NULL,
+// ignored deletes are run as if without the truncate optimization,
+// and other ignored actions are silently skipped or denied.
+//
+// https://sqlite.org/c3ref/c_deny.html
+func Ignore(name string, match func(Action) bool) Rule {
+	return Rule{name, match, sqlite3.AUTH_IGNORE}
+}
+
+// Policy is an ordered list of rules.
+type Policy []Rule
+
+// Join concatenates policies, in order.
+func Join(policies ...Policy) Policy {
+	return slices.Concat(policies...)
+}
+
+// Check returns the result for the action,
+// and the rule that decided it, or nil if no rule matched.
+func (p Policy) Check(a Action) (sqlite3.AuthorizerReturnCode, *Rule) {
+	for i := range p {
+		if r := &p[i]; r.Match(a) {
+			return r.Result, r
+		}
+	}
+	return sqlite3.AUTH_OK, nil
+}
+
+// Denial describes an action denied or ignored by a rule.
+type Denial struct {
+	Action Action
+	Rule   string
+}
+
+// Error implements the error interface.
+func (d Denial) Error() string {
+	return fmt.Sprintf("authz: rule %q denied action %d (%q, %q)",
+		d.Rule, d.Action.Code, d.Action.Name3rd, d.Action.Name4th)
+}
+
+// Authorizer returns a callback for [sqlite3.Conn.SetAuthorizer]
+// that enforces the policy.
+// If denied is not nil, it's called for every action
+// the policy denies or ignores, to report the rule that decided it.
+func (p Policy) Authorizer(denied func(Denial)) func(action sqlite3.AuthorizerActionCode, name3rd, name4th, schema, inner string) sqlite3.AuthorizerReturnCode {
+	return func(action sqlite3.AuthorizerActionCode, name3rd, name4th, schema, inner string) sqlite3.AuthorizerReturnCode {
+		a := Action{action, name3rd, name4th, schema, inner}
+		res, rule := p.Check(a)
+		if res != sqlite3.AUTH_OK && denied != nil {
+			denied(Denial{a, rule.Name})
+		}
+		return res
+	}
+}
+
+// ReadOnly returns a rule that denies all writes:
+// inserts, updates, deletes, schema changes,
+// and attaching or detaching databases, which may create files.
+// PRAGMA statements, which may also write,
+// are left to [AllowPragmas].
+func ReadOnly() Rule {
+	return Deny("read-only", func(a Action) bool {
+		switch a.Code {
+		case sqlite3.AUTH_READ, sqlite3.AUTH_SELECT,
+			sqlite3.AUTH_TRANSACTION, sqlite3.AUTH_SAVEPOINT,
+			sqlite3.AUTH_FUNCTION, sqlite3.AUTH_RECURSIVE,
+			sqlite3.AUTH_PRAGMA:
+			return false
+		}
+		return true
+	})
+}
+
+// AllowTables returns a rule that denies reading or writing tables
+// other than those named, and the internal schema tables.
+// Table names are case-insensitive.
+func AllowTables(tables ...string) Rule {
+	return Deny("allow-tables", func(a Action) bool {
+		switch a.Code {
+		case sqlite3.AUTH_READ, sqlite3.AUTH_INSERT,
+			sqlite3.AUTH_UPDATE, sqlite3.AUTH_DELETE:
+			return !contains(tables, a.Name3rd) && !isSchemaTable(a.Name3rd)
+		}
+		return false
+	})
+}
+
+// AllowColumns returns a rule that denies reading or updating
+// columns of table other than those named.
+// Names are case-insensitive.
+func AllowColumns(table string, columns ...string) Rule {
+	return Deny("allow-columns:"+table, func(a Action) bool {
+		switch a.Code {
+		case sqlite3.AUTH_READ, sqlite3.AUTH_UPDATE:
+			return strings.EqualFold(a.Name3rd, table) &&
+				a.Name4th != "" && !contains(columns, a.Name4th)
+		}
+		return false
+	})
+}
+
+// MaskColumns returns a rule that ignores reads of the named columns of table,
+// so they read as NULL.
+// Names are case-insensitive.
+func MaskColumns(table string, columns ...string) Rule {
+	return Ignore("mask-columns:"+table, func(a Action) bool {
+		return a.Code == sqlite3.AUTH_READ &&
+			strings.EqualFold(a.Name3rd, table) &&
+			contains(columns, a.Name4th)
+	})
+}
+
+// DenyAttach returns a rule that denies attaching and detaching databases.
+func DenyAttach() Rule {
+	return Deny("deny-attach", func(a Action) bool {
+		return a.Code == sqlite3.AUTH_ATTACH || a.Code == sqlite3.AUTH_DETACH
+	})
+}
+
+// AllowPragmas returns a rule that denies PRAGMA statements
+// other than those named.
+// Names are case-insensitive.
+func AllowPragmas(pragmas ...string) Rule {
+	return Deny("allow-pragmas", func(a Action) bool {
+		return a.Code == sqlite3.AUTH_PRAGMA && !contains(pragmas, a.Name3rd)
+	})
+}
+
+// AllowFunctions returns a rule that denies calling SQL functions
+// other than those named.
+// Names are case-insensitive.
+func AllowFunctions(functions ...string) Rule {
+	return Deny("allow-functions", func(a Action) bool {
+		return a.Code == sqlite3.AUTH_FUNCTION && !contains(functions, a.Name4th)
+	})
+}
+
+// DenyFunctions returns a rule that denies calling the named SQL functions.
+// Names are case-insensitive.
+func DenyFunctions(functions ...string) Rule {
+	return Deny("deny-functions", func(a Action) bool {
+		return a.Code == sqlite3.AUTH_FUNCTION && contains(functions, a.Name4th)
+	})
+}
+
+func contains(names []string, name string) bool {
+	return slices.ContainsFunc(names, func(n string) bool {
+		return strings.EqualFold(n, name)
+	})
+}
+
+func isSchemaTable(name string) bool {
+	switch strings.ToLower(name) {
+	case "sqlite_schema", "sqlite_master",
+		"sqlite_temp_schema", "sqlite_temp_master":
+		return true
+	}
+	return false
+}
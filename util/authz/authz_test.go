@@ -0,0 +1,103 @@
+package authz_test
+
+import (
+	"testing"
+
+	"github.com/ncruces/go-sqlite3"
+	_ "github.com/ncruces/go-sqlite3/embed"
+	_ "github.com/ncruces/go-sqlite3/internal/testcfg"
+	"github.com/ncruces/go-sqlite3/util/authz"
+)
+
+func TestPolicy(t *testing.T) {
+	t.Parallel()
+
+	db, err := sqlite3.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = db.Exec(`
+		CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, password TEXT);
+		CREATE TABLE secrets (value TEXT);
+		INSERT INTO users VALUES (1, 'alice', 'hunter2');
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy := authz.Join(
+		authz.Policy{
+			authz.ReadOnly(),
+			authz.DenyAttach(),
+			authz.AllowPragmas("table_info"),
+			authz.DenyFunctions("randomblob"),
+		},
+		authz.Policy{
+			authz.MaskColumns("users", "password"),
+			authz.AllowTables("users"),
+		},
+	)
+
+	var denial *authz.Denial
+	err = db.SetAuthorizer(policy.Authorizer(func(d authz.Denial) {
+		denial = &d
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stmt, _, err := db.Prepare(`SELECT name, password FROM users`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+
+	if !stmt.Step() {
+		t.Fatal(stmt.Err())
+	}
+	if got := stmt.ColumnText(0); got != "alice" {
+		t.Errorf("got %q", got)
+	}
+	if got := stmt.ColumnType(1); got != sqlite3.NULL {
+		t.Errorf("got %v, want NULL", got)
+	}
+	if denial == nil || denial.Rule != "mask-columns:users" {
+		t.Errorf("got %v", denial)
+	}
+	stmt.Close()
+
+	tests := []struct {
+		sql  string
+		rule string
+	}{
+		{`INSERT INTO users (name) VALUES ('bob')`, "read-only"},
+		{`DROP TABLE users`, "read-only"},
+		{`SELECT * FROM secrets`, "allow-tables"},
+		{`ATTACH ':memory:' AS other`, "read-only"},
+		{`DETACH other`, "read-only"},
+		{`PRAGMA user_version = 1`, "allow-pragmas"},
+		{`SELECT randomblob(8)`, "deny-functions"},
+	}
+	for _, tt := range tests {
+		denial = nil
+		err := db.Exec(tt.sql)
+		if err == nil {
+			t.Errorf("%s: want error", tt.sql)
+		}
+		if denial == nil || denial.Rule != tt.rule {
+			t.Errorf("%s: got %v, want rule %q", tt.sql, denial, tt.rule)
+		}
+	}
+
+	err = db.Exec(`PRAGMA table_info(users)`)
+	if err != nil {
+		t.Error(err)
+	}
+
+	res, rule := authz.Policy{authz.DenyAttach()}.Check(authz.Action{Code: sqlite3.AUTH_ATTACH})
+	if res != sqlite3.AUTH_DENY || rule == nil || rule.Name != "deny-attach" {
+		t.Errorf("got %v, %v", res, rule)
+	}
+}
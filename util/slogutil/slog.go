@@ -0,0 +1,136 @@
+// Package slogutil sends SQLite traces and error logs
+// to a [slog.Logger].
+//
+//	err := slogutil.Trace(db, slog.Default(), &slogutil.TraceOptions{
+//		SlowThreshold: 100 * time.Millisecond,
+//	})
+package slogutil
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/ncruces/go-sqlite3"
+)
+
+// TraceOptions configure [Trace].
+type TraceOptions struct {
+	// Level is the level of statement records.
+	// The default is [slog.LevelDebug].
+	Level slog.Leveler
+	// SlowThreshold, if positive, is the duration above which
+	// statements are logged as slow queries, at [slog.LevelWarn].
+	SlowThreshold time.Duration
+	// ExpandedSQL logs the SQL with bound parameters expanded.
+	// This may log sensitive data.
+	ExpandedSQL bool
+	// Status logs the performance counters of the statement.
+	Status bool
+}
+
+// Trace installs a tracer that logs a record to logger
+// every time a prepared statement finishes running,
+// with its SQL, duration and the number of rows it returned.
+//
+// Only statements prepared with [sqlite3.Conn.Prepare] are traced.
+// Trace replaces any callback set by [sqlite3.Conn.Trace].
+//
+// https://sqlite.org/c3ref/trace_v2.html
+func Trace(c *sqlite3.Conn, logger *slog.Logger, opts *TraceOptions) error {
+	var o TraceOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Level == nil {
+		o.Level = slog.LevelDebug
+	}
+
+	rows := map[*sqlite3.Stmt]int{}
+	return c.Trace(sqlite3.TRACE_STMT|sqlite3.TRACE_PROFILE|sqlite3.TRACE_ROW|sqlite3.TRACE_CLOSE,
+		func(evt sqlite3.TraceEvent, arg1, arg2 any) error {
+			switch evt {
+			case sqlite3.TRACE_STMT:
+				rows[arg1.(*sqlite3.Stmt)] = 0
+
+			case sqlite3.TRACE_ROW:
+				rows[arg1.(*sqlite3.Stmt)]++
+
+			case sqlite3.TRACE_PROFILE:
+				stmt := arg1.(*sqlite3.Stmt)
+				n := rows[stmt]
+				delete(rows, stmt)
+				logProfile(c, logger, &o, stmt, time.Duration(arg2.(int64)), n)
+
+			case sqlite3.TRACE_CLOSE:
+				clear(rows)
+			}
+			return nil
+		})
+}
+
+func logProfile(c *sqlite3.Conn, logger *slog.Logger, o *TraceOptions, stmt *sqlite3.Stmt, d time.Duration, rows int) {
+	msg := "sqlite3: query"
+	level := o.Level.Level()
+	if o.SlowThreshold > 0 && d >= o.SlowThreshold {
+		msg = "sqlite3: slow query"
+		level = max(level, slog.LevelWarn)
+	}
+
+	ctx := c.GetInterrupt()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := make([]slog.Attr, 0, 5)
+	attrs = append(attrs,
+		slog.String("sql", stmt.SQL()),
+		slog.Duration("duration", d),
+		slog.Int("rows", rows))
+	if o.ExpandedSQL {
+		attrs = append(attrs, slog.String("expanded_sql", stmt.ExpandedSQL()))
+	}
+	if o.Status {
+		attrs = append(attrs, slog.Group("status",
+			slog.Int("fullscan_step", stmt.Status(sqlite3.STMTSTATUS_FULLSCAN_STEP, false)),
+			slog.Int("sort", stmt.Status(sqlite3.STMTSTATUS_SORT, false)),
+			slog.Int("autoindex", stmt.Status(sqlite3.STMTSTATUS_AUTOINDEX, false)),
+			slog.Int("vm_step", stmt.Status(sqlite3.STMTSTATUS_VM_STEP, false)),
+			slog.Int("reprepare", stmt.Status(sqlite3.STMTSTATUS_REPREPARE, false)),
+			slog.Int("run", stmt.Status(sqlite3.STMTSTATUS_RUN, false)),
+			slog.Int("filter_miss", stmt.Status(sqlite3.STMTSTATUS_FILTER_MISS, false)),
+			slog.Int("filter_hit", stmt.Status(sqlite3.STMTSTATUS_FILTER_HIT, false)),
+			slog.Int("memused", stmt.Status(sqlite3.STMTSTATUS_MEMUSED, false))))
+	}
+	logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// ConfigLog routes the error log of the connection to logger.
+// [sqlite3.NOTICE] messages are logged at [slog.LevelInfo],
+// [sqlite3.WARNING] messages at [slog.LevelWarn],
+// and all other messages at [slog.LevelError].
+// ConfigLog replaces any callback set by [sqlite3.Conn.ConfigLog].
+//
+// https://sqlite.org/errlog.html
+func ConfigLog(c *sqlite3.Conn, logger *slog.Logger) error {
+	return c.ConfigLog(func(code sqlite3.ExtendedErrorCode, msg string) {
+		logger.LogAttrs(context.Background(), LogLevel(code), msg,
+			slog.Int("code", int(code)),
+			slog.String("error", code.Error()))
+	})
+}
+
+// LogLevel maps an SQLite error code to a log level.
+func LogLevel(code sqlite3.ExtendedErrorCode) slog.Level {
+	switch code.Code() {
+	case sqlite3.NOTICE:
+		return slog.LevelInfo
+	case sqlite3.WARNING:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
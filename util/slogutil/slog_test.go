@@ -0,0 +1,113 @@
+package slogutil_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/ncruces/go-sqlite3"
+	_ "github.com/ncruces/go-sqlite3/embed"
+	_ "github.com/ncruces/go-sqlite3/internal/testcfg"
+	"github.com/ncruces/go-sqlite3/util/slogutil"
+)
+
+func TestTrace(t *testing.T) {
+	t.Parallel()
+
+	db, err := sqlite3.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	err = slogutil.Trace(db, logger, &slogutil.TraceOptions{
+		ExpandedSQL: true,
+		Status:      true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stmt, _, err := db.Prepare(`SELECT value FROM generate_series(1, ?)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+
+	stmt.BindInt(1, 3)
+	if err := stmt.Exec(); err != nil {
+		t.Fatal(err)
+	}
+
+	got := buf.String()
+	for _, want := range []string{
+		`level=DEBUG`,
+		`msg="sqlite3: query"`,
+		`sql="SELECT value FROM generate_series(1, ?)"`,
+		`expanded_sql="SELECT value FROM generate_series(1, 3)"`,
+		`rows=3`,
+		`status.run=1`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+
+	buf.Reset()
+	err = slogutil.Trace(db, logger, &slogutil.TraceOptions{SlowThreshold: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slow, _, err := db.Prepare(`
+		WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x+1 FROM c WHERE x < 1000000)
+		SELECT count(*) FROM c`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer slow.Close()
+
+	if err := slow.Exec(); err != nil {
+		t.Fatal(err)
+	}
+
+	got = buf.String()
+	if !strings.Contains(got, `level=WARN msg="sqlite3: slow query"`) {
+		t.Errorf("got %q", got)
+	}
+	if strings.Contains(got, `expanded_sql`) {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestConfigLog(t *testing.T) {
+	t.Parallel()
+
+	db, err := sqlite3.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	err = slogutil.ConfigLog(db, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.Log(sqlite3.WARNING_AUTOINDEX, "automatic index on %s", "t(x)")
+	if got := buf.String(); !strings.Contains(got, `level=WARN msg="automatic index on t(x)" code=284`) {
+		t.Errorf("got %q", got)
+	}
+
+	buf.Reset()
+	db.Exec(`SELECT * FRM sqlite_schema`)
+	if got := buf.String(); !strings.Contains(got, `level=ERROR msg="near \"FRM\": syntax error`) {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,355 @@
+// Package replica implements an SQLite VFS for read replicas.
+//
+// The "replica" [vfs.VFS] presents a read-only database,
+// built from a base snapshot and the WAL segments
+// shipped by [github.com/ncruces/go-sqlite3/vfs/walship].
+// Segments are applied as they arrive,
+// and become visible to connections without reopening them.
+// Each read transaction sees a consistent snapshot of the database,
+// as of the last segment applied when it started.
+//
+// Segments can be read from a [walship.Dir]:
+//
+//	dir, err := walship.NewDir("backups")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	rdb, err := replica.Load(dir, "demo.db")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	replica.Create("demo.db", rdb)
+//	go rdb.Follow(ctx, dir, "demo.db", time.Second)
+//
+//	db, err := sqlite3.Open("file:demo.db?vfs=replica")
+//
+// Or received from a [Chan] in the same process.
+//
+// A DB holds the entire database in Go memory.
+// Pages are shared between versions,
+// so applying a segment only copies
+// the parts of the page table it changes.
+//
+// Importing package replica registers the VFS:
+//
+//	import _ "github.com/ncruces/go-sqlite3/vfs/replica"
+package replica
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ncruces/go-sqlite3/vfs"
+	"github.com/ncruces/go-sqlite3/vfs/walship"
+)
+
+func init() {
+	vfs.Register("replica", replicaVFS{})
+}
+
+var (
+	replicaMtx sync.RWMutex
+	// +checklocks:replicaMtx
+	replicaDBs = map[string]*DB{}
+)
+
+// Create creates a replica database from db.
+func Create(name string, db *DB) {
+	replicaMtx.Lock()
+	defer replicaMtx.Unlock()
+	replicaDBs[name] = db
+}
+
+// Delete deletes a replica database.
+func Delete(name string) {
+	replicaMtx.Lock()
+	defer replicaMtx.Unlock()
+	delete(replicaDBs, name)
+}
+
+// DB holds the contents of a replica database, in memory.
+// A DB is safe for concurrent use by multiple goroutines.
+type DB struct {
+	mtx  sync.Mutex
+	next uint64 // +checklocks:mtx
+	cur  atomic.Pointer[version]
+}
+
+// version is an immutable view of the database.
+type version struct {
+	chunks   []*chunk // Chunks are shared between versions.
+	pages    int
+	first    []byte // The first page, with a patched header.
+	pageSize int
+	counter  uint32
+}
+
+const chunkSize = 512
+
+// chunk holds a run of pages of a version.
+// nil pages are zero-filled,
+// as are the pages past the end of the database.
+type chunk [chunkSize][]byte
+
+// page returns page i, counting from 0.
+func (v *version) page(i int) []byte {
+	if c := v.chunks[i/chunkSize]; c != nil {
+		return c[i%chunkSize]
+	}
+	return nil
+}
+
+// New creates a DB from base, the contents of a database file.
+// The DB takes ownership of base,
+// and the caller should not use base after this call.
+func New(base []byte) (*DB, error) {
+	var v version
+	if len(base) > 0 {
+		if len(base) < 100 {
+			return nil, errors.New("replica: invalid database")
+		}
+		v.pageSize = int(binary.BigEndian.Uint16(base[16:]))
+		if v.pageSize == 1 {
+			v.pageSize = 65536
+		}
+		if v.pageSize < 512 || len(base)%v.pageSize != 0 {
+			return nil, errors.New("replica: invalid database")
+		}
+		v.pages = len(base) / v.pageSize
+		v.chunks = make([]*chunk, (v.pages+chunkSize-1)/chunkSize)
+		for i := range v.pages {
+			c := v.chunks[i/chunkSize]
+			if c == nil {
+				c = new(chunk)
+				v.chunks[i/chunkSize] = c
+			}
+			c[i%chunkSize] = base[:v.pageSize:v.pageSize]
+			base = base[v.pageSize:]
+		}
+	}
+	v.patch()
+
+	db := &DB{next: 1}
+	db.cur.Store(&v)
+	return db, nil
+}
+
+// Load creates a DB from the latest snapshot
+// of the named database stored in dir,
+// and applies the segments written after it.
+func Load(dir *walship.Dir, name string) (*DB, error) {
+	r, start, err := dir.OpenSnapshot(name)
+	if err != nil {
+		return nil, err
+	}
+	base, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := New(base)
+	if err != nil {
+		return nil, err
+	}
+	db.mtx.Lock()
+	db.next = start
+	db.mtx.Unlock()
+
+	if _, err := db.Update(dir, name); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Apply applies the segment of a transaction to the database.
+// Connections see the transaction from their next read transaction.
+// The DB takes ownership of the segment,
+// and the caller should not modify it after this call.
+func (db *DB) Apply(seg *walship.Segment) error {
+	db.mtx.Lock()
+	defer db.mtx.Unlock()
+	return db.apply(seg)
+}
+
+// +checklocks:db.mtx
+func (db *DB) apply(seg *walship.Segment) error {
+	old := db.cur.Load()
+	if old.pageSize != 0 && old.pageSize != seg.PageSize {
+		return errors.New("replica: page size changed")
+	}
+	for _, f := range seg.Frames {
+		if f.Page == 0 || len(f.Data) != seg.PageSize {
+			return errors.New("replica: invalid segment")
+		}
+	}
+
+	v := version{
+		pages:    int(seg.Pages),
+		pageSize: seg.PageSize,
+		counter:  old.counter + 1,
+	}
+	v.chunks = make([]*chunk, (v.pages+chunkSize-1)/chunkSize)
+	copy(v.chunks, old.chunks)
+
+	// Copy the chunks changed by this segment.
+	copied := make([]bool, len(v.chunks))
+	write := func(i int) *chunk {
+		c := v.chunks[i]
+		if !copied[i] {
+			if c == nil {
+				c = new(chunk)
+			} else {
+				cp := *c
+				c = &cp
+			}
+			v.chunks[i] = c
+			copied[i] = true
+		}
+		return c
+	}
+	if n := v.pages % chunkSize; n != 0 && v.pages < old.pages {
+		// Clear the pages past the end of the database.
+		clear(write(len(v.chunks) - 1)[n:])
+	}
+	for _, f := range seg.Frames {
+		if i := int(f.Page) - 1; i < v.pages {
+			write(i / chunkSize)[i%chunkSize] = f.Data
+		}
+	}
+	v.patch()
+
+	db.cur.Store(&v)
+	return nil
+}
+
+// Update applies the segments of the named database
+// written to dir since they were last applied,
+// and returns how many were applied.
+func (db *DB) Update(dir *walship.Dir, name string) (int, error) {
+	db.mtx.Lock()
+	defer db.mtx.Unlock()
+
+	var n int
+	for {
+		seg, err := dir.ReadSegment(name, db.next)
+		if errors.Is(err, os.ErrNotExist) {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		if err := db.apply(seg); err != nil {
+			return n, err
+		}
+		db.next++
+		n++
+	}
+}
+
+// Follow calls [DB.Update] every interval,
+// which must be positive,
+// until ctx is done or an error occurs.
+func (db *DB) Follow(ctx context.Context, dir *walship.Dir, name string, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := db.Update(dir, name); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Receive applies the segments sent to c,
+// until c is closed, ctx is done, or an error occurs.
+// Once Receive returns, sending to c fails.
+func (db *DB) Receive(ctx context.Context, c *Chan) (err error) {
+	defer func() { c.stop(err) }()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case seg := <-c.segs:
+			if err := db.Apply(seg); err != nil {
+				return err
+			}
+		case <-c.done:
+			// Closed: apply the segments already sent.
+			for {
+				select {
+				case seg := <-c.segs:
+					if err := db.Apply(seg); err != nil {
+						return err
+					}
+				default:
+					return nil
+				}
+			}
+		}
+	}
+}
+
+// Chan is a [walship.Sink] that sends the segments
+// of a single database to a [DB] in the same process,
+// through a buffered channel.
+// Sending blocks when the channel is full,
+// delaying the transactions of the primary,
+// and fails once the channel is closed,
+// or [DB.Receive] returns.
+type Chan struct {
+	segs chan *walship.Segment
+	done chan struct{}
+	once sync.Once
+	err  error
+}
+
+// NewChan creates a Chan that buffers up to size segments.
+func NewChan(size int) *Chan {
+	return &Chan{
+		segs: make(chan *walship.Segment, size),
+		done: make(chan struct{}),
+	}
+}
+
+// WriteSegment implements [walship.Sink].
+func (c *Chan) WriteSegment(name string, seg *walship.Segment) error {
+	select {
+	case <-c.done:
+		return c.err
+	default:
+	}
+	select {
+	case c.segs <- seg:
+		return nil
+	case <-c.done:
+		return c.err
+	}
+}
+
+// Close closes the channel.
+// [DB.Receive] returns after applying the segments already sent.
+func (c *Chan) Close() error {
+	c.stop(nil)
+	return nil
+}
+
+func (c *Chan) stop(err error) {
+	c.once.Do(func() {
+		if err == nil {
+			err = errors.New("replica: channel closed")
+		}
+		c.err = err
+		close(c.done)
+	})
+}
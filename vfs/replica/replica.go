@@ -0,0 +1,172 @@
+package replica
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/ncruces/go-sqlite3"
+	"github.com/ncruces/go-sqlite3/vfs"
+)
+
+type replicaVFS struct{}
+
+func (replicaVFS) Open(name string, flags vfs.OpenFlag) (vfs.File, vfs.OpenFlag, error) {
+	if flags&vfs.OPEN_MAIN_DB == 0 {
+		// notest
+		return nil, flags, sqlite3.CANTOPEN
+	}
+	replicaMtx.RLock()
+	defer replicaMtx.RUnlock()
+	if db, ok := replicaDBs[name]; ok {
+		return &replicaFile{db: db}, flags | vfs.OPEN_READONLY, nil
+	}
+	return nil, flags, sqlite3.CANTOPEN
+}
+
+func (replicaVFS) Delete(name string, dirSync bool) error {
+	// notest
+	return sqlite3.IOERR_DELETE
+}
+
+func (replicaVFS) Access(name string, flag vfs.AccessFlag) (bool, error) {
+	return false, nil // used to check for journals
+}
+
+func (replicaVFS) FullPathname(name string) (string, error) {
+	return name, nil
+}
+
+type replicaFile struct {
+	db   *DB
+	snap *version // The version seen by the current read transaction.
+	lock vfs.LockLevel
+}
+
+var (
+	// Ensure these interfaces are implemented:
+	_ vfs.FileLockState = &replicaFile{}
+)
+
+func (r *replicaFile) version() *version {
+	if r.snap != nil {
+		return r.snap
+	}
+	return r.db.cur.Load()
+}
+
+func (r *replicaFile) Close() error {
+	return r.Unlock(vfs.LOCK_NONE)
+}
+
+func (r *replicaFile) ReadAt(b []byte, off int64) (n int, err error) {
+	v := r.version()
+	if v.pageSize == 0 {
+		return 0, io.EOF
+	}
+	for n < len(b) {
+		pgno := off / int64(v.pageSize)
+		if pgno >= int64(v.pages) {
+			return n, io.EOF
+		}
+		var page []byte
+		if pgno == 0 {
+			page = v.first
+		} else {
+			page = v.page(int(pgno))
+		}
+		rest := int(off % int64(v.pageSize))
+		m := len(b[n:])
+		if page == nil {
+			m = min(m, v.pageSize-rest)
+			clear(b[n : n+m])
+		} else {
+			m = copy(b[n:], page[rest:])
+		}
+		n += m
+		off += int64(m)
+	}
+	return n, nil
+}
+
+func (r *replicaFile) WriteAt(b []byte, off int64) (n int, err error) {
+	// notest
+	return 0, sqlite3.READONLY
+}
+
+func (r *replicaFile) Truncate(size int64) error {
+	// notest
+	return sqlite3.READONLY
+}
+
+func (r *replicaFile) Sync(flag vfs.SyncFlag) error {
+	// notest
+	return nil
+}
+
+func (r *replicaFile) Size() (int64, error) {
+	v := r.version()
+	return int64(v.pages) * int64(v.pageSize), nil
+}
+
+func (r *replicaFile) Lock(lock vfs.LockLevel) error {
+	if lock > vfs.LOCK_SHARED {
+		// notest // the database is read-only
+		return sqlite3.READONLY
+	}
+	if r.lock == vfs.LOCK_NONE {
+		// Start a read transaction on the latest version.
+		r.snap = r.db.cur.Load()
+	}
+	r.lock = lock
+	return nil
+}
+
+func (r *replicaFile) Unlock(lock vfs.LockLevel) error {
+	if lock == vfs.LOCK_NONE {
+		r.snap = nil
+	}
+	r.lock = lock
+	return nil
+}
+
+func (r *replicaFile) CheckReservedLock() (bool, error) {
+	// notest
+	return false, nil
+}
+
+func (r *replicaFile) SectorSize() int {
+	// notest
+	return 0
+}
+
+func (r *replicaFile) DeviceCharacteristics() vfs.DeviceCharacteristic {
+	return vfs.IOCAP_SUBPAGE_READ
+}
+
+func (r *replicaFile) LockState() vfs.LockLevel {
+	return r.lock
+}
+
+// patch prepares the first page of a version.
+//
+// The database is presented in rollback journal mode,
+// and the change counter is set to the version counter,
+// so that connections notice the database changed,
+// and discard their page cache.
+//
+// https://sqlite.org/fileformat.html#the_database_header
+func (v *version) patch() {
+	v.counter = max(v.counter, 1)
+	if v.pages == 0 {
+		v.first = nil
+		return
+	}
+	first := make([]byte, v.pageSize)
+	copy(first, v.page(0))
+	first[18] = 1
+	first[19] = 1
+	binary.BigEndian.PutUint32(first[24:], v.counter)
+	binary.BigEndian.PutUint32(first[28:], uint32(v.pages))
+	binary.BigEndian.PutUint32(first[92:], v.counter)
+	v.first = first
+}
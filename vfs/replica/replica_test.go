@@ -0,0 +1,410 @@
+package replica_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ncruces/go-sqlite3"
+	_ "github.com/ncruces/go-sqlite3/embed"
+	_ "github.com/ncruces/go-sqlite3/internal/testcfg"
+	"github.com/ncruces/go-sqlite3/vfs"
+	"github.com/ncruces/go-sqlite3/vfs/replica"
+	"github.com/ncruces/go-sqlite3/vfs/walship"
+)
+
+func TestLoad(t *testing.T) {
+	if !vfs.SupportsSharedMemory {
+		t.Skip("skipping without shared memory")
+	}
+	t.Parallel()
+
+	dir, err := walship.NewDir(filepath.Join(t.TempDir(), "backups"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vfs.Register("replica-dir", walship.Wrap(vfs.Find(""), dir))
+
+	primary := openPrimary(t, "replica-dir")
+	name := primary.Filename("").Database()
+	insert(t, primary, 10)
+
+	err = dir.Snapshot(context.Background(), name, primary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	insert(t, primary, 10)
+
+	rdb, err := replica.Load(dir, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replica.Create("load.db", rdb)
+	defer replica.Delete("load.db")
+
+	db, err := sqlite3.Open("file:load.db?vfs=replica")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if got := count(t, db); got != 20 {
+		t.Errorf("got %d rows, want 20", got)
+	}
+
+	insert(t, primary, 5)
+	n, err := rdb.Update(dir, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 5 {
+		t.Errorf("got %d segments, want 5", n)
+	}
+	if got := count(t, db); got != 25 {
+		t.Errorf("got %d rows, want 25", got)
+	}
+
+	err = db.Exec(`INSERT INTO test VALUES (1)`)
+	if !errors.Is(err, sqlite3.READONLY) {
+		t.Errorf("got %v, want READONLY", err)
+	}
+
+	insert(t, primary, 5)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = rdb.Follow(ctx, dir, name, time.Second)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+	if got := count(t, db); got != 30 {
+		t.Errorf("got %d rows, want 30", got)
+	}
+}
+
+func TestLoad_pruned(t *testing.T) {
+	if !vfs.SupportsSharedMemory {
+		t.Skip("skipping without shared memory")
+	}
+	t.Parallel()
+
+	backups := filepath.Join(t.TempDir(), "backups")
+	dir, err := walship.NewDir(backups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vfs.Register("replica-pruned", walship.Wrap(vfs.Find(""), dir))
+
+	primary := openPrimary(t, "replica-pruned")
+	name := primary.Filename("").Database()
+	insert(t, primary, 10)
+
+	err = dir.Snapshot(context.Background(), name, primary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	segs, err := filepath.Glob(filepath.Join(backups, "*", "*.seg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, seg := range segs {
+		if err := os.Remove(seg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	insert(t, primary, 3)
+
+	rdb, err := replica.Load(dir, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replica.Create("pruned.db", rdb)
+	defer replica.Delete("pruned.db")
+
+	db, err := sqlite3.Open("file:pruned.db?vfs=replica")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if got := count(t, db); got != 13 {
+		t.Errorf("got %d rows, want 13", got)
+	}
+
+	insert(t, primary, 2)
+	n, err := rdb.Update(dir, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("got %d segments, want 2", n)
+	}
+	if got := count(t, db); got != 15 {
+		t.Errorf("got %d rows, want 15", got)
+	}
+}
+
+func TestChan(t *testing.T) {
+	if !vfs.SupportsSharedMemory {
+		t.Skip("skipping without shared memory")
+	}
+	t.Parallel()
+
+	ch := replica.NewChan(100)
+	vfs.Register("replica-chan", walship.Wrap(vfs.Find(""), ch))
+
+	rdb, err := replica.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replica.Create("chan.db", rdb)
+	defer replica.Delete("chan.db")
+
+	errs := make(chan error, 1)
+	go func() { errs <- rdb.Receive(context.Background(), ch) }()
+
+	open := func() *sqlite3.Conn {
+		t.Helper()
+		db, err := sqlite3.Open("file:chan.db?vfs=replica")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return db
+	}
+
+	// Segments are applied asynchronously.
+	wait := func(db *sqlite3.Conn, want int) {
+		t.Helper()
+		for range 1000 {
+			stmt, _, err := db.Prepare(`SELECT count(*) FROM test`)
+			if err == nil {
+				ok := stmt.Step() && stmt.ColumnInt(0) == want
+				stmt.Close()
+				if ok {
+					return
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("timed out waiting for %d rows", want)
+	}
+
+	primary := openPrimary(t, "replica-chan")
+	insert(t, primary, 10)
+
+	db := open()
+	wait(db, 10)
+
+	// A read transaction doesn't see segments applied after it started.
+	err = db.Exec(`BEGIN`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := count(t, db); got != 10 {
+		t.Errorf("got %d rows, want 10", got)
+	}
+	insert(t, primary, 10)
+	wait(open(), 20)
+	if got := count(t, db); got != 10 {
+		t.Errorf("got %d rows, want 10", got)
+	}
+	err = db.Exec(`COMMIT`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := count(t, db); got != 20 {
+		t.Errorf("got %d rows, want 20", got)
+	}
+
+	err = primary.Exec(`DELETE FROM test WHERE rowid % 2 = 0`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = primary.WALCheckpoint("main", sqlite3.CHECKPOINT_TRUNCATE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	insert(t, primary, 5)
+
+	ch.Close()
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	if got := count(t, db); got != 15 {
+		t.Errorf("got %d rows, want 15", got)
+	}
+	err = db.Exec(`PRAGMA integrity_check`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Once closed, the primary fails to commit.
+	err = primary.Exec(`INSERT INTO test VALUES (1)`)
+	if !errors.Is(err, sqlite3.IOERR) {
+		t.Errorf("got %v, want IOERR", err)
+	}
+}
+
+func TestChan_Receive(t *testing.T) {
+	t.Parallel()
+
+	rdb, err := replica.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Sending fails once Receive returns.
+	ch := replica.NewChan(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = rdb.Receive(ctx, ch)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+	err = ch.WriteSegment("test.db", &walship.Segment{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+
+	// A segment that fails to apply stops Receive.
+	ch = replica.NewChan(1)
+	err = ch.WriteSegment("test.db", &walship.Segment{
+		PageSize: 4096,
+		Pages:    1,
+		Frames:   []walship.Frame{{Page: 1, Data: make([]byte, 512)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rdb.Receive(context.Background(), ch)
+	if err == nil {
+		t.Fatal("want error")
+	}
+	err = ch.WriteSegment("test.db", &walship.Segment{})
+	if err == nil {
+		t.Error("want error")
+	}
+}
+
+func TestDB_Apply(t *testing.T) {
+	t.Parallel()
+
+	rdb, err := replica.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replica.Create("apply.db", rdb)
+	defer replica.Delete("apply.db")
+
+	const pageSize = 512
+	apply := func(pages uint32, frames map[uint32]byte) {
+		t.Helper()
+		seg := walship.Segment{PageSize: pageSize, Pages: pages}
+		for pgno, b := range frames {
+			seg.Frames = append(seg.Frames, walship.Frame{
+				Page: pgno,
+				Data: bytes.Repeat([]byte{b}, pageSize),
+			})
+		}
+		if err := rdb.Apply(&seg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	open := func() vfs.File {
+		t.Helper()
+		f, _, err := vfs.Find("replica").Open("apply.db", vfs.OPEN_MAIN_DB|vfs.OPEN_READONLY)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { f.Close() })
+		if err := f.Lock(vfs.LOCK_SHARED); err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+	check := func(f vfs.File, pages int, want map[uint32]byte) {
+		t.Helper()
+		size, err := f.Size()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size != int64(pages)*pageSize {
+			t.Errorf("got size %d, want %d", size, pages*pageSize)
+		}
+		buf := make([]byte, pageSize)
+		for pgno, b := range want {
+			_, err := f.ReadAt(buf, int64(pgno-1)*pageSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf, bytes.Repeat([]byte{b}, pageSize)) {
+				t.Errorf("page %d: got %d, want %d", pgno, buf[0], b)
+			}
+		}
+	}
+
+	// Span several chunks of the page table.
+	frames := map[uint32]byte{}
+	for pgno := uint32(2); pgno <= 1200; pgno++ {
+		frames[pgno] = byte(pgno)
+	}
+	apply(1200, frames)
+	old := open()
+
+	// Shrink, then grow: pages past the end are zeros.
+	apply(600, map[uint32]byte{3: 0xff, 700: 0xff})
+	apply(1100, map[uint32]byte{1100: 0xee})
+
+	check(open(), 1100, map[uint32]byte{
+		3: 0xff, 599: 599 % 256, 600: 600 % 256,
+		601: 0, 700: 0, 1099: 0, 1100: 0xee,
+	})
+	// A read transaction keeps its version.
+	check(old, 1200, map[uint32]byte{
+		3: 3, 600: 600 % 256, 700: 700 % 256, 1100: 1100 % 256,
+	})
+}
+
+func openPrimary(t testing.TB, vfs string) *sqlite3.Conn {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "test.db")
+	db, err := sqlite3.Open("file:" + filepath.ToSlash(name) +
+		"?vfs=" + vfs + "&_pragma=journal_mode(wal)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	err = db.Exec(`CREATE TABLE test (col)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func insert(t testing.TB, db *sqlite3.Conn, n int) {
+	t.Helper()
+	for range n {
+		err := db.Exec(`INSERT INTO test VALUES (randomblob(1000))`)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func count(t testing.TB, db *sqlite3.Conn) int {
+	t.Helper()
+	stmt, _, err := db.Prepare(`SELECT count(*) FROM test`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	if !stmt.Step() {
+		t.Fatal(stmt.Err())
+	}
+	return stmt.ColumnInt(0)
+}
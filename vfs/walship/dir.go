@@ -0,0 +1,389 @@
+package walship
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncruces/go-sqlite3"
+)
+
+// Dir is a [Sink] that stores segments and snapshots in a local directory.
+//
+// Each database is stored in a subdirectory named after
+// the absolute name of its file, escaped.
+// Relative names are resolved against the working directory.
+// Segments are numbered in commit order.
+// Snapshots are named after the last segment written
+// before and after they were taken, and the commit time of the latter.
+type Dir struct {
+	path string
+	mtx  sync.Mutex
+	seq  map[string]uint64
+}
+
+// NewDir creates a Dir that stores segments and snapshots in path.
+func NewDir(path string) (*Dir, error) {
+	if err := os.MkdirAll(path, 0777); err != nil {
+		return nil, err
+	}
+	return &Dir{path: path, seq: map[string]uint64{}}, nil
+}
+
+const (
+	segmentExt  = ".seg"
+	snapshotExt = ".db"
+)
+
+func (d *Dir) dir(name string) string {
+	if abs, err := filepath.Abs(name); err == nil {
+		name = abs
+	}
+	return filepath.Join(d.path, url.QueryEscape(name))
+}
+
+// last returns the number of the last segment of a database,
+// even if it was removed after a snapshot.
+// It must be called with the mutex held.
+func (d *Dir) last(name string) (uint64, error) {
+	if seq, ok := d.seq[d.dir(name)]; ok {
+		return seq, nil
+	}
+	segs, snaps, err := d.list(name)
+	if err != nil {
+		return 0, err
+	}
+	var seq uint64
+	if len(segs) > 0 {
+		seq = segs[len(segs)-1]
+	}
+	for _, s := range snaps {
+		seq = max(seq, s.end)
+	}
+	d.seq[d.dir(name)] = seq
+	return seq, nil
+}
+
+// WriteSegment implements [Sink].
+func (d *Dir) WriteSegment(name string, seg *Segment) error {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	seq, err := d.last(name)
+	if err != nil {
+		return err
+	}
+	seq++
+
+	dir := d.dir(name)
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+	err = writeFile(filepath.Join(dir, fmt.Sprintf("%020d%s", seq, segmentExt)), func(w io.Writer) error {
+		var hdr [20]byte
+		binary.BigEndian.PutUint64(hdr[0:], uint64(seg.Time.UnixNano()))
+		binary.BigEndian.PutUint32(hdr[8:], uint32(seg.PageSize))
+		binary.BigEndian.PutUint32(hdr[12:], seg.Pages)
+		binary.BigEndian.PutUint32(hdr[16:], uint32(len(seg.Frames)))
+		if _, err := w.Write(hdr[:]); err != nil {
+			return err
+		}
+		for _, f := range seg.Frames {
+			if _, err := w.Write(binary.BigEndian.AppendUint32(nil, f.Page)); err != nil {
+				return err
+			}
+			if _, err := w.Write(f.Data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	d.seq[d.dir(name)] = seq
+	return nil
+}
+
+// Snapshot takes a base snapshot of the named database,
+// backing it up from db with [sqlite3.Conn.BackupTo].
+// Once it's taken, segments numbered before the last one
+// written before the snapshot started may be removed.
+func (d *Dir) Snapshot(ctx context.Context, name string, db *sqlite3.Conn) error {
+	d.mtx.Lock()
+	start, err := d.last(name)
+	d.mtx.Unlock()
+	if err != nil {
+		return err
+	}
+
+	dir := d.dir(name)
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, fmt.Sprintf("%020d", start)+".tmp")
+	err = writeFile(tmp, func(w io.Writer) error {
+		return db.BackupTo(ctx, "main", w, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	d.mtx.Lock()
+	end, err := d.last(name)
+	d.mtx.Unlock()
+	if err != nil {
+		return err
+	}
+	// The snapshot has no commits after the last segment written,
+	// so it can restore any point in time after that commit.
+	snap := snapshot{start: start, end: end}
+	if end > 0 {
+		seg, err := readSegment(filepath.Join(dir, fmt.Sprintf("%020d%s", end, segmentExt)), true)
+		if err != nil {
+			return err
+		}
+		snap.time = seg.Time.UnixNano()
+	}
+	// The last segment before the snapshot may not be visible to it:
+	// restores apply it again.
+	err = os.Rename(tmp, filepath.Join(dir, snap.name()))
+	if err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// Restore rebuilds the named database into the new file dst,
+// from the latest suitable snapshot, and the segments
+// of transactions committed up to until.
+// If until is zero, all segments are applied.
+func (d *Dir) Restore(name, dst string, until time.Time) error {
+	d.mtx.Lock()
+	_, snaps, err := d.list(name)
+	d.mtx.Unlock()
+	if err != nil {
+		return err
+	}
+	dir := d.dir(name)
+
+	// Find the latest snapshot that doesn't go past until.
+	snap := -1
+	for i, s := range snaps {
+		if !until.IsZero() && s.time > until.UnixNano() {
+			continue
+		}
+		if snap < 0 || s.end >= snaps[snap].end {
+			snap = i
+		}
+	}
+	if snap < 0 {
+		return errors.New("walship: no snapshot to restore from")
+	}
+
+	f, err := os.OpenFile(dst, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	src, err := os.Open(filepath.Join(dir, snaps[snap].name()))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, src)
+	src.Close()
+	if err != nil {
+		return err
+	}
+
+	// Segments up to the end of the snapshot may be partly in it,
+	// and must all be applied; later ones, up to until.
+	for seq := max(snaps[snap].start, 1); ; seq++ {
+		seg, err := readSegment(filepath.Join(dir, fmt.Sprintf("%020d%s", seq, segmentExt)), false)
+		if seq > snaps[snap].end {
+			if errors.Is(err, os.ErrNotExist) {
+				break
+			}
+			if err == nil && !until.IsZero() && seg.Time.After(until) {
+				break
+			}
+		}
+		if err != nil {
+			return err
+		}
+		for _, fr := range seg.Frames {
+			if _, err := f.WriteAt(fr.Data, int64(fr.Page-1)*int64(seg.PageSize)); err != nil {
+				return err
+			}
+		}
+		if err := f.Truncate(int64(seg.Pages) * int64(seg.PageSize)); err != nil {
+			return err
+		}
+	}
+
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// OpenSnapshot opens the latest snapshot of the named database,
+// and returns the number of the first segment to apply to it.
+// If segments were removed, that's the first one remaining.
+func (d *Dir) OpenSnapshot(name string) (io.ReadCloser, uint64, error) {
+	d.mtx.Lock()
+	segs, snaps, err := d.list(name)
+	var last uint64
+	if err == nil {
+		last, err = d.last(name)
+	}
+	d.mtx.Unlock()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	snap := -1
+	for i, s := range snaps {
+		if snap < 0 || s.end > snaps[snap].end {
+			snap = i
+		}
+	}
+	if snap < 0 {
+		return nil, 0, errors.New("walship: no snapshot to open")
+	}
+
+	f, err := os.Open(filepath.Join(d.dir(name), snaps[snap].name()))
+	if err != nil {
+		return nil, 0, err
+	}
+	i, _ := slices.BinarySearch(segs, max(snaps[snap].start, 1))
+	if i < len(segs) {
+		return f, segs[i], nil
+	}
+	return f, last + 1, nil
+}
+
+// ReadSegment reads segment seq of the named database.
+// If the segment hasn't been written yet,
+// the error wraps [os.ErrNotExist].
+func (d *Dir) ReadSegment(name string, seq uint64) (*Segment, error) {
+	return readSegment(filepath.Join(d.dir(name), fmt.Sprintf("%020d%s", seq, segmentExt)), false)
+}
+
+// snapshot identifies a snapshot file.
+type snapshot struct {
+	start, end uint64
+	time       int64
+}
+
+func (s snapshot) name() string {
+	return fmt.Sprintf("%020d-%020d-%020d%s", s.start, s.end, s.time, snapshotExt)
+}
+
+// list returns the numbers of the segments,
+// and the snapshots, of a database.
+func (d *Dir) list(name string) (segs []uint64, snaps []snapshot, err error) {
+	entries, err := os.ReadDir(d.dir(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, e := range entries {
+		var seq uint64
+		var snap snapshot
+		switch n := e.Name(); {
+		case strings.HasSuffix(n, segmentExt):
+			if _, err := fmt.Sscanf(n, "%d"+segmentExt, &seq); err == nil {
+				segs = append(segs, seq)
+			}
+		case strings.HasSuffix(n, snapshotExt):
+			if _, err := fmt.Sscanf(n, "%d-%d-%d"+snapshotExt, &snap.start, &snap.end, &snap.time); err == nil {
+				snaps = append(snaps, snap)
+			}
+		}
+	}
+	slices.Sort(segs)
+	return segs, snaps, nil
+}
+
+// writeFile atomically writes a file,
+// syncing it and its directory to disk.
+func writeFile(path string, write func(io.Writer) error) error {
+	tmp := path + ".part"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	if err := write(w); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// readSegment reads a segment file,
+// or only its header.
+func readSegment(path string, header bool) (*Segment, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+
+	var hdr [20]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, err
+	}
+	seg := Segment{
+		Time:     time.Unix(0, int64(binary.BigEndian.Uint64(hdr[0:]))),
+		PageSize: int(binary.BigEndian.Uint32(hdr[8:])),
+		Pages:    binary.BigEndian.Uint32(hdr[12:]),
+	}
+	if header {
+		return &seg, nil
+	}
+
+	n := binary.BigEndian.Uint32(hdr[16:])
+	seg.Frames = make([]Frame, n)
+	for i := range seg.Frames {
+		var page [4]byte
+		if _, err := io.ReadFull(r, page[:]); err != nil {
+			return nil, err
+		}
+		data := make([]byte, seg.PageSize)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		seg.Frames[i] = Frame{binary.BigEndian.Uint32(page[:]), data}
+	}
+	return &seg, nil
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package walship
+
+// syncDir is a no-op where directories can't be synced.
+func syncDir(path string) error {
+	return nil
+}
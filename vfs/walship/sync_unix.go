@@ -0,0 +1,16 @@
+//go:build unix
+
+package walship
+
+import "os"
+
+// syncDir syncs a directory to disk,
+// making renames in it durable.
+func syncDir(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
@@ -0,0 +1,363 @@
+// Package walship wraps an SQLite VFS to ship WAL frames,
+// for continuous backups.
+//
+// The wrapped VFS observes writes to the WAL of databases in WAL mode,
+// and sends the frames of each committed transaction to a [Sink].
+// [Dir] is a Sink that stores them in a local directory,
+// along with base snapshots,
+// and restores databases from them, to any point in time:
+//
+//	sink, err := walship.NewDir("backups")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	vfs.Register("walship", walship.Wrap(vfs.Find(""), sink))
+//
+//	db, err := sqlite3.Open("file:demo.db?vfs=walship&_pragma=journal_mode(wal)")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	err = sink.Snapshot(ctx, "demo.db", db)
+//
+// A segment is sent before the commit frame of its transaction
+// is written to the WAL, and an error from the Sink fails the transaction.
+// If writing the commit frame fails after the Sink stored the segment,
+// the Sink holds a transaction the database doesn't.
+//
+// https://sqlite.org/walformat.html
+package walship
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"time"
+
+	"github.com/ncruces/go-sqlite3"
+	"github.com/ncruces/go-sqlite3/util/vfsutil"
+	"github.com/ncruces/go-sqlite3/vfs"
+)
+
+// Sink receives the WAL segments of databases.
+type Sink interface {
+	// WriteSegment stores the segment of a transaction
+	// committed to the named database.
+	// Segments of a database are written in commit order,
+	// and should be durable when WriteSegment returns.
+	// The segment is not reused, and may be retained.
+	WriteSegment(name string, seg *Segment) error
+}
+
+// SinkCheckpoint can be implemented by a [Sink]
+// to be notified of checkpoints,
+// for example, to take a new snapshot, or prune old segments.
+type SinkCheckpoint interface {
+	Sink
+	// CheckpointDone is called after a checkpoint of the named database.
+	CheckpointDone(name string)
+}
+
+// Segment holds the frames of a committed transaction.
+type Segment struct {
+	Time     time.Time // When the transaction was committed.
+	PageSize int       // The database page size.
+	Pages    uint32    // The size of the database, in pages, after the transaction.
+	Frames   []Frame   // The pages written by the transaction, in order.
+}
+
+// Frame is a page written to the WAL.
+type Frame struct {
+	Page uint32 // The page number, starting at 1.
+	Data []byte // The page contents.
+}
+
+// Wrap wraps a base VFS to create a VFS
+// that sends the WAL segments of databases to sink.
+func Wrap(base vfs.VFS, sink Sink) vfs.VFS {
+	return &shipVFS{VFS: base, sink: sink}
+}
+
+type shipVFS struct {
+	vfs.VFS
+	sink Sink
+}
+
+func (s *shipVFS) Open(name string, flags vfs.OpenFlag) (vfs.File, vfs.OpenFlag, error) {
+	// notest // OpenFilename is called instead
+	return nil, 0, sqlite3.CANTOPEN
+}
+
+func (s *shipVFS) OpenFilename(name *vfs.Filename, flags vfs.OpenFlag) (file vfs.File, _ vfs.OpenFlag, err error) {
+	file, flags, err = vfsutil.WrapOpenFilename(s.VFS, name, flags)
+	if err != nil || name == nil || flags&vfs.OPEN_MEMORY != 0 {
+		return file, flags, err
+	}
+
+	switch {
+	case flags&vfs.OPEN_MAIN_DB != 0:
+		return &dbFile{File: file, name: name.Database(), sink: s.sink}, flags, nil
+	case flags&vfs.OPEN_WAL != 0:
+		return &walFile{File: file, name: name.Database(), sink: s.sink}, flags, nil
+	}
+	return file, flags, nil
+}
+
+const (
+	walHeaderSize      = 32
+	walFrameHeaderSize = 24
+)
+
+type walFile struct {
+	vfs.File
+	sink     Sink
+	name     string
+	salt     []byte // The salts of the WAL header, read for each commit.
+	pageSize int64  // The page size of the WAL header, read for each commit.
+	commit   []byte // The header of a commit frame not yet written.
+	start    int64  // The offset of that commit frame.
+}
+
+func (w *walFile) WriteAt(p []byte, off int64) (n int, err error) {
+	// SQLite writes the header of a frame, then its page.
+	// Hold back the header of a commit frame,
+	// and ship the transaction when its page is written,
+	// so that a transaction the Sink failed to store
+	// is never committed to the WAL.
+	if w.commit != nil {
+		frame, start := w.commit, w.start
+		w.commit = nil
+		if off != start+walFrameHeaderSize || int64(len(p)) != w.pageSize {
+			return 0, sqlite3.IOERR_WRITE
+		}
+		frame = append(frame, p...)
+		if err := w.ship(start, frame); err != nil {
+			return 0, err
+		}
+		if _, err := w.File.WriteAt(frame, start); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+
+	commit, written, err := w.isCommit(p, off)
+	if err != nil {
+		return 0, err
+	}
+	if commit {
+		frame := p
+		if len(p) == walFrameHeaderSize {
+			if !written {
+				w.commit = append([]byte(nil), p...)
+				w.start = off
+				return len(p), nil
+			}
+			frame = make([]byte, walFrameHeaderSize+w.pageSize)
+			copy(frame, p)
+			if _, err := w.File.ReadAt(frame[walFrameHeaderSize:], off+walFrameHeaderSize); err != nil {
+				return 0, err
+			}
+		}
+		if err := w.ship(off, frame); err != nil {
+			return 0, err
+		}
+	}
+	return w.File.WriteAt(p, off)
+}
+
+// isCommit reports whether p, written at off,
+// starts a commit frame: a frame with a database size,
+// and the salts of the current WAL header.
+// Other connections may reset the WAL between transactions,
+// so the WAL header is read again for each commit.
+//
+// If the transaction rewrote frames in place,
+// SQLite writes its frames without salts,
+// then rewrites their headers after the commit frame's page.
+// This reports whether that page was written.
+func (w *walFile) isCommit(p []byte, off int64) (commit, written bool, err error) {
+	// Pages are written alone, and their sizes are powers of two.
+	n := len(p)
+	if off < walHeaderSize || n < walFrameHeaderSize || n&(n-1) == 0 ||
+		binary.BigEndian.Uint32(p[4:]) == 0 {
+		return false, false, nil
+	}
+
+	var hdr [walHeaderSize]byte
+	if _, err := w.File.ReadAt(hdr[:], 0); err != nil {
+		return false, false, err
+	}
+	pageSize := int64(binary.BigEndian.Uint32(hdr[8:]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 {
+		return false, false, sqlite3.CORRUPT
+	}
+
+	frameSize := walFrameHeaderSize + pageSize
+	if (off-walHeaderSize)%frameSize != 0 ||
+		n != walFrameHeaderSize && int64(n) != frameSize ||
+		!bytes.Equal(p[8:16], hdr[16:24]) {
+		return false, false, nil
+	}
+	w.salt = append(w.salt[:0], hdr[16:24]...)
+	w.pageSize = pageSize
+
+	if n == walFrameHeaderSize {
+		var frame [walFrameHeaderSize]byte
+		if _, err := w.File.ReadAt(frame[:], off); err != nil && err != io.EOF {
+			return false, false, err
+		}
+		written = bytes.Equal(frame[:8], p[:8]) && [8]byte(frame[8:16]) == [8]byte{}
+	}
+	return true, written, nil
+}
+
+// ship sends the frames of the transaction,
+// which ends with the commit frame at offset end.
+// The transaction starts after the previous commit frame,
+// or the WAL header.
+func (w *walFile) ship(end int64, commit []byte) error {
+	frameSize := walFrameHeaderSize + w.pageSize
+	frames := [][]byte{commit}
+	for off := end - frameSize; off >= walHeaderSize; off -= frameSize {
+		frame := make([]byte, frameSize)
+		if _, err := w.File.ReadAt(frame, off); err != nil {
+			return err
+		}
+		if binary.BigEndian.Uint32(frame[4:]) != 0 && bytes.Equal(frame[8:16], w.salt) {
+			if len(frames) == 1 && bytes.Equal(frame[:8], commit[:8]) &&
+				bytes.Equal(frame[walFrameHeaderSize:], commit[walFrameHeaderSize:]) {
+				// Padding after a commit repeats its frame.
+				return nil
+			}
+			break
+		}
+		frames = append(frames, frame)
+	}
+
+	seg := Segment{
+		Time:     time.Now(),
+		PageSize: int(w.pageSize),
+		Pages:    binary.BigEndian.Uint32(commit[4:]),
+	}
+	for i := len(frames) - 1; i >= 0; i-- {
+		frame := frames[i]
+		seg.Frames = append(seg.Frames, Frame{
+			Page: binary.BigEndian.Uint32(frame[0:]),
+			Data: frame[walFrameHeaderSize:],
+		})
+	}
+	if err := w.sink.WriteSegment(w.name, &seg); err != nil {
+		return sqlite3.IOERR_WRITE
+	}
+	return nil
+}
+
+func (w *walFile) Truncate(size int64) error {
+	w.commit = nil
+	return w.File.Truncate(size)
+}
+
+func (w *walFile) Unwrap() vfs.File {
+	return w.File
+}
+
+func (w *walFile) SizeHint(size int64) error {
+	return vfsutil.WrapSizeHint(w.File, size) // notest
+}
+
+func (w *walFile) ChunkSize(size int) {
+	vfsutil.WrapChunkSize(w.File, size) // notest
+}
+
+type dbFile struct {
+	vfs.File
+	sink Sink
+	name string
+}
+
+func (d *dbFile) CheckpointStart() {
+	vfsutil.WrapCheckpointStart(d.File)
+}
+
+func (d *dbFile) CheckpointDone() {
+	vfsutil.WrapCheckpointDone(d.File)
+	if s, ok := d.sink.(SinkCheckpoint); ok {
+		s.CheckpointDone(d.name)
+	}
+}
+
+func (d *dbFile) Unwrap() vfs.File {
+	return d.File
+}
+
+func (d *dbFile) SharedMemory() vfs.SharedMemory {
+	return vfsutil.WrapSharedMemory(d.File)
+}
+
+// Wrap optional methods.
+
+func (d *dbFile) LockState() vfs.LockLevel {
+	return vfsutil.WrapLockState(d.File) // notest
+}
+
+func (d *dbFile) PersistWAL() bool {
+	return vfsutil.WrapPersistWAL(d.File) // notest
+}
+
+func (d *dbFile) SetPersistWAL(keepWAL bool) {
+	vfsutil.WrapSetPersistWAL(d.File, keepWAL) // notest
+}
+
+func (d *dbFile) PowersafeOverwrite() bool {
+	return vfsutil.WrapPowersafeOverwrite(d.File) // notest
+}
+
+func (d *dbFile) SetPowersafeOverwrite(psow bool) {
+	vfsutil.WrapSetPowersafeOverwrite(d.File, psow) // notest
+}
+
+func (d *dbFile) ChunkSize(size int) {
+	vfsutil.WrapChunkSize(d.File, size) // notest
+}
+
+func (d *dbFile) SizeHint(size int64) error {
+	return vfsutil.WrapSizeHint(d.File, size) // notest
+}
+
+func (d *dbFile) HasMoved() (bool, error) {
+	return vfsutil.WrapHasMoved(d.File) // notest
+}
+
+func (d *dbFile) Overwrite() error {
+	return vfsutil.WrapOverwrite(d.File) // notest
+}
+
+func (d *dbFile) SyncSuper(super string) error {
+	return vfsutil.WrapSyncSuper(d.File, super) // notest
+}
+
+func (d *dbFile) CommitPhaseTwo() error {
+	return vfsutil.WrapCommitPhaseTwo(d.File) // notest
+}
+
+func (d *dbFile) BeginAtomicWrite() error {
+	return vfsutil.WrapBeginAtomicWrite(d.File) // notest
+}
+
+func (d *dbFile) CommitAtomicWrite() error {
+	return vfsutil.WrapCommitAtomicWrite(d.File) // notest
+}
+
+func (d *dbFile) RollbackAtomicWrite() error {
+	return vfsutil.WrapRollbackAtomicWrite(d.File) // notest
+}
+
+func (d *dbFile) Pragma(name string, value string) (string, error) {
+	return vfsutil.WrapPragma(d.File, name, value) // notest
+}
+
+func (d *dbFile) BusyHandler(handler func() bool) {
+	vfsutil.WrapBusyHandler(d.File, handler) // notest
+}
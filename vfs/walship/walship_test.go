@@ -0,0 +1,462 @@
+package walship_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ncruces/go-sqlite3"
+	_ "github.com/ncruces/go-sqlite3/embed"
+	_ "github.com/ncruces/go-sqlite3/internal/testcfg"
+	"github.com/ncruces/go-sqlite3/vfs"
+	"github.com/ncruces/go-sqlite3/vfs/walship"
+)
+
+type checkpointSink struct {
+	*walship.Dir
+	checkpoints atomic.Int32
+}
+
+func (s *checkpointSink) CheckpointDone(name string) {
+	s.checkpoints.Add(1)
+}
+
+func TestDir(t *testing.T) {
+	if !vfs.SupportsSharedMemory {
+		t.Skip("skipping without shared memory")
+	}
+	t.Parallel()
+
+	backups := filepath.Join(t.TempDir(), "backups")
+	dir, err := walship.NewDir(backups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := &checkpointSink{Dir: dir}
+	vfs.Register("walship-test", walship.Wrap(vfs.Find(""), sink))
+
+	tmp := t.TempDir()
+	name := filepath.Join(tmp, "test.db")
+	db, err := sqlite3.Open("file:" + filepath.ToSlash(name) +
+		"?vfs=walship-test&_pragma=journal_mode(wal)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	insert := func(n int) {
+		t.Helper()
+		for range n {
+			err := db.Exec(`INSERT INTO test VALUES (randomblob(1000))`)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	err = db.Exec(`CREATE TABLE test (col)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	insert(10)
+
+	err = dir.Snapshot(context.Background(), name, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	snapshot := time.Now()
+	time.Sleep(10 * time.Millisecond)
+
+	insert(10)
+	_, _, err = db.WALCheckpoint("main", sqlite3.CHECKPOINT_TRUNCATE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sink.checkpoints.Load() == 0 {
+		t.Error("want checkpoints")
+	}
+	insert(10)
+
+	time.Sleep(10 * time.Millisecond)
+	until := time.Now()
+	time.Sleep(10 * time.Millisecond)
+
+	insert(10)
+	err = db.Exec(`DELETE FROM test WHERE rowid % 2 = 0`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count := func(path string) (n int) {
+		t.Helper()
+		db, err := sqlite3.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		err = db.Exec(`PRAGMA integrity_check`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stmt, _, err := db.Prepare(`SELECT count(*) FROM test`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer stmt.Close()
+		if !stmt.Step() {
+			t.Fatal(stmt.Err())
+		}
+		return stmt.ColumnInt(0)
+	}
+
+	latest := filepath.Join(tmp, "latest.db")
+	err = dir.Restore(name, latest, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := count(latest); got != 20 {
+		t.Errorf("got %d rows, want 20", got)
+	}
+
+	earlier := filepath.Join(tmp, "earlier.db")
+	err = dir.Restore(name, earlier, until)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := count(earlier); got != 30 {
+		t.Errorf("got %d rows, want 30", got)
+	}
+
+	err = dir.Restore(name, earlier, until)
+	if err == nil {
+		t.Error("want error")
+	}
+	err = dir.Restore(name, filepath.Join(tmp, "before.db"), snapshot.Add(-time.Hour))
+	if err == nil {
+		t.Error("want error")
+	}
+	err = dir.Restore("other.db", filepath.Join(tmp, "other.db"), time.Time{})
+	if err == nil {
+		t.Error("want error")
+	}
+
+	// Remove the segments the snapshot doesn't need.
+	snaps, err := filepath.Glob(filepath.Join(backups, "*", "*.db"))
+	if err != nil || len(snaps) != 1 {
+		t.Fatal(snaps, err)
+	}
+	var start uint64
+	fmt.Sscanf(filepath.Base(snaps[0]), "%d-", &start)
+	segs, err := filepath.Glob(filepath.Join(backups, "*", "*.seg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, seg := range segs {
+		var seq uint64
+		fmt.Sscanf(filepath.Base(seg), "%d", &seq)
+		if seq < start {
+			if err := os.Remove(seg); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	pruned := filepath.Join(tmp, "pruned.db")
+	err = dir.Restore(name, pruned, snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := count(pruned); got != 10 {
+		t.Errorf("got %d rows, want 10", got)
+	}
+
+	pruned = filepath.Join(tmp, "pruned-earlier.db")
+	err = dir.Restore(name, pruned, until)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := count(pruned); got != 30 {
+		t.Errorf("got %d rows, want 30", got)
+	}
+
+	// Numbering continues past the snapshot, even without segments.
+	var end uint64
+	fmt.Sscanf(filepath.Base(snaps[0]), "%d-%d-", &start, &end)
+	segs, err = filepath.Glob(filepath.Join(backups, "*", "*.seg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, seg := range segs {
+		if err := os.Remove(seg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reopen, err := walship.NewDir(backups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = reopen.WriteSegment(name, &walship.Segment{Time: time.Now(), PageSize: 4096})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reopen.ReadSegment(name, end+1); err != nil {
+		t.Error(err)
+	}
+}
+
+type errSink struct {
+	*walship.Dir
+	fail atomic.Bool
+}
+
+func (s *errSink) WriteSegment(name string, seg *walship.Segment) error {
+	if s.fail.Load() {
+		return errors.New("sink failed")
+	}
+	return s.Dir.WriteSegment(name, seg)
+}
+
+func TestWrap_errors(t *testing.T) {
+	if !vfs.SupportsSharedMemory {
+		t.Skip("skipping without shared memory")
+	}
+	t.Parallel()
+
+	dir, err := walship.NewDir(filepath.Join(t.TempDir(), "backups"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := &errSink{Dir: dir}
+	vfs.Register("walship-errors", walship.Wrap(vfs.Find(""), sink))
+
+	tmp := t.TempDir()
+	name := filepath.Join(tmp, "test.db")
+	db, err := sqlite3.Open("file:" + filepath.ToSlash(name) +
+		"?vfs=walship-errors&_pragma=journal_mode(wal)&_pragma=cache_size(10)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = db.Exec(`CREATE TABLE test (col)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = dir.Snapshot(context.Background(), name, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A transaction larger than the cache spills frames to the WAL,
+	// and rewrites them before it commits.
+	err = db.Exec(`
+		BEGIN;
+		INSERT INTO test SELECT randomblob(1000) FROM generate_series(1, 100);
+		UPDATE test SET col = randomblob(1000);
+		COMMIT;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sink.fail.Store(true)
+	err = db.Exec(`INSERT INTO test VALUES (randomblob(1000))`)
+	if !errors.Is(err, sqlite3.IOERR) {
+		t.Errorf("got %v, want IOERR", err)
+	}
+	sink.fail.Store(false)
+
+	count := func(path string) (n int) {
+		t.Helper()
+		db, err := sqlite3.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		err = db.Exec(`PRAGMA integrity_check`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stmt, _, err := db.Prepare(`SELECT count(*) FROM test`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer stmt.Close()
+		if !stmt.Step() {
+			t.Fatal(stmt.Err())
+		}
+		return stmt.ColumnInt(0)
+	}
+
+	// Recover a copy of the database and its WAL, as after a crash:
+	// the failed transaction must not be in it.
+	crash := filepath.Join(tmp, "crash.db")
+	for _, ext := range []string{"", "-wal"} {
+		data, err := os.ReadFile(name + ext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(crash+ext, data, 0666)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := count(crash); got != 100 {
+		t.Errorf("got %d rows, want 100", got)
+	}
+
+	latest := filepath.Join(tmp, "latest.db")
+	err = dir.Restore(name, latest, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := count(latest); got != 100 {
+		t.Errorf("got %d rows, want 100", got)
+	}
+
+	err = db.Exec(`INSERT INTO test VALUES (randomblob(1000))`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := count(name); got != 101 {
+		t.Errorf("got %d rows, want 101", got)
+	}
+}
+
+func TestWrap_connections(t *testing.T) {
+	if !vfs.SupportsSharedMemory {
+		t.Skip("skipping without shared memory")
+	}
+	t.Parallel()
+
+	dir, err := walship.NewDir(filepath.Join(t.TempDir(), "backups"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vfs.Register("walship-connections", walship.Wrap(vfs.Find(""), dir))
+
+	tmp := t.TempDir()
+	name := filepath.Join(tmp, "test.db")
+	open := func() *sqlite3.Conn {
+		t.Helper()
+		db, err := sqlite3.Open("file:" + filepath.ToSlash(name) +
+			"?vfs=walship-connections&_pragma=journal_mode(wal)&_pragma=cache_size(10)")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return db
+	}
+	exec := func(db *sqlite3.Conn, sql string) {
+		t.Helper()
+		if err := db.Exec(sql); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a := open()
+	b := open()
+
+	exec(a, `CREATE TABLE test (col)`)
+	err = dir.Snapshot(context.Background(), name, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exec(a, `INSERT INTO test VALUES (randomblob(1000))`)
+
+	// Another connection resets the WAL.
+	exec(b, `PRAGMA wal_checkpoint(TRUNCATE)`)
+	exec(b, `INSERT INTO test VALUES (randomblob(1000))`)
+	exec(a, `INSERT INTO test VALUES (randomblob(1000))`)
+
+	// A transaction spills frames to the WAL, and rolls back;
+	// another connection overwrites them.
+	exec(a, `
+		BEGIN;
+		INSERT INTO test SELECT randomblob(1000) FROM generate_series(1, 100);
+	`)
+	exec(a, `ROLLBACK`)
+	exec(b, `INSERT INTO test VALUES (randomblob(1000))`)
+	exec(a, `INSERT INTO test VALUES (randomblob(1000))`)
+
+	// Another connection restarts the WAL.
+	exec(b, `PRAGMA wal_checkpoint(RESTART)`)
+	exec(b, `INSERT INTO test VALUES (randomblob(1000))`)
+	exec(a, `INSERT INTO test VALUES (randomblob(1000))`)
+
+	latest := filepath.Join(tmp, "latest.db")
+	err = dir.Restore(name, latest, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sqlite3.Open(latest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	exec(db, `PRAGMA integrity_check`)
+	stmt, _, err := db.Prepare(`SELECT count(*) FROM test`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	if !stmt.Step() {
+		t.Fatal(stmt.Err())
+	}
+	if got := stmt.ColumnInt(0); got != 7 {
+		t.Errorf("got %d rows, want 7", got)
+	}
+}
+
+func TestDir_names(t *testing.T) {
+	t.Parallel()
+
+	dir, err := walship.NewDir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Databases with the same base name are stored apart.
+	a := filepath.Join(t.TempDir(), "app.db")
+	b := filepath.Join(t.TempDir(), "app.db")
+	for i, name := range []string{a, b, b} {
+		err := dir.WriteSegment(name, &walship.Segment{
+			Time:     time.Now(),
+			PageSize: 4096,
+			Pages:    uint32(i),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	seg, err := dir.ReadSegment(a, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seg.Pages != 0 {
+		t.Errorf("got %d pages, want 0", seg.Pages)
+	}
+	seg, err = dir.ReadSegment(b, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seg.Pages != 2 {
+		t.Errorf("got %d pages, want 2", seg.Pages)
+	}
+	_, err = dir.ReadSegment(a, 2)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("got %v, want os.ErrNotExist", err)
+	}
+}